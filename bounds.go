@@ -0,0 +1,761 @@
+package genshinartis
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+/**
+Upper bounds of the damage reachable from groups of builds, used by findTop to skip the ones that can't beat the builds found so far.
+They rely on the damage never decreasing when a stat increases, which holds for every stat artifacts and sets can give.
+**/
+
+const slotCount = 5
+
+// jobsPerWorker is how many groups of builds findTop makes for every worker, so they stay busy when the groups take different times
+const jobsPerWorker = 8
+
+// weightProbe is how much every stat is raised by to find its weight, see buildBounds.weightsAt
+const weightProbe = 10
+
+// seedLeaders is how many pieces of every main stat findTop searches first, see candidates.leaders
+const seedLeaders = 3
+
+// candidates are the pieces a group of builds can use in every slot, indexed by artifactSlot
+type candidates [slotCount][]*Artifact
+
+// statArray holds every stat of a piece, faster to go through than a map when bounding many builds
+type statArray [BaseDEF + 1]float32
+
+// pieceStats returns the main stat and substats of an artifact
+func pieceStats(a *Artifact) map[stat]float32 {
+	s := map[stat]float32{a.MainStat: a.MainStatValue}
+	for _, sub := range a.SubStats {
+		if sub != nil {
+			s[sub.Stat] = s[sub.Stat] + sub.Value
+		}
+	}
+	return s
+}
+
+// artifactsBySlot groups the artifacts by slot
+func artifactsBySlot(artifacts []*Artifact) candidates {
+	var bySlot candidates
+	for _, art := range artifacts {
+		bySlot[art.Slot] = append(bySlot[art.Slot], art)
+	}
+	return bySlot
+}
+
+// size returns the amount of builds that can be made from the candidates
+func (c candidates) size() int {
+	size := 1
+	for _, arts := range c {
+		size *= len(arts)
+	}
+	return size
+}
+
+// boundPiece is an artifact as the bounds see it
+type boundPiece struct {
+	stats         statArray
+	relevant      []float32 // the values of buildBounds.relevantPieceStats
+	set           int       // index in buildBounds.sets
+	twoPieceKind  int       // index in buildBounds.twoPieceKinds
+	fourPieceKind int       // index in buildBounds.fourPieceKinds
+}
+
+// buildBounds holds what's needed to bound the damage of the builds that can be made from a pool of artifacts
+type buildBounds struct {
+	config     optimizationConfig
+	pieces     map[*Artifact]*boundPiece
+	pieceStats []stat // the stats any of the pieces has
+	sets       []setEffects
+	setIndex   map[artifactSet]int // in sets
+
+	twoPieceKinds  []setKind
+	twoPieceCombos []setCombo
+	fourPieceKinds []setKind
+	relevant       map[stat]bool // see relevantStats
+	// the relevant stats any of the pieces has, in the order of pieceStats
+	relevantPieceStats []stat
+	// the sum of the max of every other stat in every slot, from all the pieces. As they don't change the damage,
+	// the bounds use them instead of the max stats of their candidates.
+	otherStats statArray
+}
+
+// setEffects are the effects of 2 and 4 pieces of a set
+type setEffects struct {
+	set         artifactSet
+	twoPiece    map[stat]float32
+	fourPiece   map[stat]float32 // including the 2 piece one
+	conversions []conversion     // of 4 pieces
+}
+
+// setKind is a group of sets with the same effects on the damage, see setKinds. Its effects are the elementwise max of theirs.
+type setKind struct {
+	sets        []int // indexes in buildBounds.sets
+	bonus       map[stat]float32
+	conversions []conversion
+	dominated   bool // its combinations can't beat the ones without it
+}
+
+// setCombo is the bonus of two kinds of 2 piece sets, or a single one if the second kind is -1
+type setCombo struct {
+	kinds [2]int
+	bonus map[stat]float32
+}
+
+func newBuildBounds(c optimizationConfig, bySlot candidates) buildBounds {
+	b := buildBounds{config: c, pieces: map[*Artifact]*boundPiece{}, setIndex: map[artifactSet]int{}}
+	hasStat := map[stat]bool{}
+	for _, arts := range bySlot {
+		for _, art := range arts {
+			piece := &boundPiece{}
+			for stat, v := range pieceStats(art) {
+				piece.stats[stat] = v
+				if !hasStat[stat] {
+					hasStat[stat] = true
+					b.pieceStats = append(b.pieceStats, stat)
+				}
+			}
+			b.pieces[art] = piece
+
+			index, ok := b.setIndex[art.Set]
+			if !ok {
+				index = len(b.sets)
+				b.setIndex[art.Set] = index
+				build := map[artifactSlot]*Artifact{SlotFlower: {Set: art.Set}, SlotPlume: {Set: art.Set}}
+				effects := setEffects{set: art.Set}
+				effects.twoPiece = artifactSetBonus(build, c.character.setConditions)
+				build[SlotSands], build[SlotGoblet] = &Artifact{Set: art.Set}, &Artifact{Set: art.Set}
+				effects.fourPiece = artifactSetBonus(build, c.character.setConditions)
+				effects.conversions = artifactSetConversions(build)
+				b.sets = append(b.sets, effects)
+			}
+			piece.set = index
+		}
+	}
+
+	b.relevant = b.relevantStats(bySlot)
+	slotMax, _ := b.maxStats(bySlot)
+	for _, stat := range b.pieceStats {
+		if b.relevant[stat] {
+			b.relevantPieceStats = append(b.relevantPieceStats, stat)
+			continue
+		}
+		for _, max := range slotMax {
+			b.otherStats[stat] += max[stat]
+		}
+	}
+	b.twoPieceKinds = b.setKinds(b.relevant, false)
+	b.twoPieceCombos = setCombos(b.twoPieceKinds)
+	b.fourPieceKinds = b.setKinds(b.relevant, true)
+	twoPieceKind, fourPieceKind := make([]int, len(b.sets)), make([]int, len(b.sets))
+	for kind, k := range b.twoPieceKinds {
+		for _, set := range k.sets {
+			twoPieceKind[set] = kind
+		}
+	}
+	for kind, k := range b.fourPieceKinds {
+		for _, set := range k.sets {
+			fourPieceKind[set] = kind
+		}
+	}
+	for _, piece := range b.pieces {
+		piece.twoPieceKind, piece.fourPieceKind = twoPieceKind[piece.set], fourPieceKind[piece.set]
+		for _, stat := range b.relevantPieceStats {
+			piece.relevant = append(piece.relevant, piece.stats[stat])
+		}
+	}
+	return b
+}
+
+// setBound returns the elementwise max bonus of the set combinations that can be made with the given amount of slots
+// that have pieces of every set, and the conversions of the 4 piece sets they can complete.
+// For a single build, they are its own set effects.
+func (b buildBounds) setBound(slotsWithSet []int) (map[stat]float32, []conversion) {
+	// two 2 piece sets can't give more than the two highest 2 piece bonuses of every stat
+	var highest, second map[stat]float32 = map[stat]float32{}, map[stat]float32{}
+	bonus := map[stat]float32{}
+	var conversions []conversion
+	for set, slots := range slotsWithSet {
+		if slots < 2 {
+			continue
+		}
+		for stat, v := range b.sets[set].twoPiece {
+			if v > highest[stat] {
+				second[stat] = highest[stat]
+				highest[stat] = v
+			} else if v > second[stat] {
+				second[stat] = v
+			}
+		}
+		if slots < 4 {
+			continue
+		}
+		for stat, v := range b.sets[set].fourPiece {
+			bonus[stat] = maxf(bonus[stat], v)
+		}
+		conversions = append(conversions, b.sets[set].conversions...)
+	}
+	for stat, v := range highest {
+		bonus[stat] = maxf(bonus[stat], v+second[stat])
+	}
+	return bonus, conversions
+}
+
+// setKinds groups the sets whose 2 or 4 piece effects give the same amounts of the relevant stats, see relevantStats,
+// so the bounds try every kind of set combination once. Sets with conversions are never grouped.
+func (b buildBounds) setKinds(relevant map[stat]bool, fourPiece bool) []setKind {
+	var kinds []setKind
+	byKey := map[string]int{}
+	for i, set := range b.sets {
+		effects, conversions := set.twoPiece, []conversion(nil)
+		if fourPiece {
+			effects, conversions = set.fourPiece, set.conversions
+		}
+		key := relevantKey(effects, relevant)
+		index, ok := byKey[key]
+		if !ok || len(conversions) > 0 || len(kinds[index].conversions) > 0 {
+			index = len(kinds)
+			byKey[key] = index
+			kinds = append(kinds, setKind{bonus: map[stat]float32{}, conversions: conversions, dominated: true})
+		}
+		k := &kinds[index]
+		k.sets = append(k.sets, i)
+		for stat, v := range effects {
+			k.bonus[stat] = maxf(k.bonus[stat], v)
+		}
+		// with no relevant effects, or none besides the 2 piece ones, the builds without the set or with 2 pieces of it do as well
+		k.dominated = k.dominated && len(conversions) == 0 && (key == "" || (fourPiece && key == relevantKey(set.twoPiece, relevant)))
+	}
+	return kinds
+}
+
+// relevantKey returns the relevant stats of the effects with their amounts, the same for effects with the same relevant ones
+func relevantKey(effects map[stat]float32, relevant map[stat]bool) string {
+	var key []string
+	for stat, v := range effects {
+		if v != 0 && relevant[stat] {
+			key = append(key, fmt.Sprint(stat, v))
+		}
+	}
+	sort.Strings(key)
+	return strings.Join(key, ",")
+}
+
+// setCombos returns every combination of two kinds of 2 piece sets, including the same kind twice, and every single kind
+func setCombos(kinds []setKind) []setCombo {
+	var combos []setCombo
+	for i := range kinds {
+		for j := i; j < len(kinds); j++ {
+			bonus := copyStats(kinds[i].bonus)
+			for stat, v := range kinds[j].bonus {
+				bonus[stat] += v
+			}
+			combos = append(combos, setCombo{kinds: [2]int{i, j}, bonus: bonus})
+		}
+	}
+	for i := range kinds {
+		combos = append(combos, setCombo{kinds: [2]int{i, -1}, bonus: kinds[i].bonus})
+	}
+	return combos
+}
+
+// bound returns the highest damage any build made from the candidates can reach,
+// calculated from the elementwise max stats of the candidates of every slot with every set combination they can make.
+// It's 0 if none of the builds can meet the constraints of the search.
+func (b buildBounds) bound(c candidates) float32 {
+	s := b.slotBoundsOf(c)
+	if !b.config.constraints.setsPossibleWith(s.hasSet) {
+		return 0
+	}
+	total := b.maxTotal(&s)
+
+	// every build either has no set bonus, or the bonus of a combination of kinds of sets with pieces in the slots they take
+	twoPiece := make([]int, len(b.twoPieceKinds))
+	for kind, k := range b.twoPieceKinds {
+		for _, set := range k.sets {
+			if s.slotsWithSet[set] >= 2 {
+				twoPiece[kind]++
+			}
+		}
+	}
+	best := b.value(&total, nil, nil)
+	for _, combo := range b.twoPieceCombos {
+		i, j := combo.kinds[0], combo.kinds[1]
+		if b.twoPieceKinds[i].dominated || (j >= 0 && b.twoPieceKinds[j].dominated) {
+			continue
+		}
+		stats := total
+		var ok bool
+		switch {
+		case j < 0 && twoPiece[i] > 0:
+			ok = b.comboStats(&stats, &s, []*kindSlots{&s.twoPiece[i]}, twoSlotPatterns)
+		case j == i && twoPiece[i] >= 2:
+			ok = b.comboStats(&stats, &s, []*kindSlots{&s.twoPiece[i]}, fourSlotPatterns)
+		case j > i && twoPiece[i] > 0 && twoPiece[j] > 0:
+			ok = b.comboStats(&stats, &s, []*kindSlots{&s.twoPiece[i], &s.twoPiece[j]}, twoByTwoSlotPatterns)
+		}
+		if ok {
+			best = maxf(best, b.value(&stats, combo.bonus, nil))
+		}
+	}
+	for kind, k := range b.fourPieceKinds {
+		if k.dominated {
+			continue
+		}
+		for _, set := range k.sets {
+			if s.slotsWithSet[set] >= 4 {
+				stats := total
+				if b.comboStats(&stats, &s, []*kindSlots{&s.fourPiece[kind]}, fourSlotPatterns) {
+					best = maxf(best, b.value(&stats, k.bonus, k.conversions))
+				}
+				break
+			}
+		}
+	}
+	return best
+}
+
+// slotBounds are the elementwise max relevant stats of the candidates of every slot, overall and by kind of set
+type slotBounds struct {
+	relevantMax  [slotCount][]float32 // of buildBounds.relevantPieceStats
+	slotsWithSet []int                // in how many slots there are pieces of every set
+	slotSets     [slotCount][]bool    // whether every slot has pieces of every set
+	setIndex     map[artifactSet]int  // see buildBounds.setIndex
+	twoPiece     []kindSlots          // by kind of 2 piece set
+	fourPiece    []kindSlots
+}
+
+// kindSlots holds the elementwise max relevant stats of the pieces of a kind of set in every slot
+type kindSlots struct {
+	max [slotCount][]float32 // of buildBounds.relevantPieceStats
+	has [slotCount]bool
+}
+
+// slotBoundsOf returns the slotBounds of the candidates, going through them once
+func (b buildBounds) slotBoundsOf(c candidates) slotBounds {
+	s := slotBounds{
+		setIndex:     b.setIndex,
+		slotsWithSet: make([]int, len(b.sets)),
+		twoPiece:     make([]kindSlots, len(b.twoPieceKinds)),
+		fourPiece:    make([]kindSlots, len(b.fourPieceKinds)),
+	}
+	// the max relevant stats of every slot, overall and by kind, share a single allocation
+	n := len(b.relevantPieceStats)
+	values := make([]float32, slotCount*(1+len(s.twoPiece)+len(s.fourPiece))*n)
+	next := func() []float32 {
+		v := values[:n:n]
+		values = values[n:]
+		return v
+	}
+	inSlot := make([]bool, slotCount*len(b.sets))
+	for slot, arts := range c {
+		s.slotSets[slot], inSlot = inSlot[:len(b.sets)], inSlot[len(b.sets):]
+		s.relevantMax[slot] = next()
+		for i := range s.twoPiece {
+			s.twoPiece[i].max[slot] = next()
+		}
+		for i := range s.fourPiece {
+			s.fourPiece[i].max[slot] = next()
+		}
+		relevantMax := s.relevantMax[slot]
+		for _, art := range arts {
+			piece := b.pieces[art]
+			two, four := &s.twoPiece[piece.twoPieceKind], &s.fourPiece[piece.fourPieceKind]
+			two.has[slot], four.has[slot] = true, true
+			twoMax, fourMax := two.max[slot], four.max[slot]
+			for i, v := range piece.relevant {
+				relevantMax[i] = maxf(relevantMax[i], v)
+				twoMax[i] = maxf(twoMax[i], v)
+				fourMax[i] = maxf(fourMax[i], v)
+			}
+			if !s.slotSets[slot][piece.set] {
+				s.slotSets[slot][piece.set] = true
+				s.slotsWithSet[piece.set]++
+			}
+		}
+	}
+	return s
+}
+
+// maxTotal returns the sum of the max stats of every slot
+func (b buildBounds) maxTotal(s *slotBounds) statArray {
+	total := b.otherStats
+	for _, max := range s.relevantMax {
+		for i, stat := range b.relevantPieceStats {
+			total[stat] += max[i]
+		}
+	}
+	return total
+}
+
+// hasSet returns true if the slot has pieces of the set
+func (s *slotBounds) hasSet(slot int, set artifactSet) bool {
+	index, ok := s.setIndex[set]
+	return ok && s.slotSets[slot][index]
+}
+
+// Slot patterns of the set combinations, see slotPatterns
+var (
+	twoSlotPatterns      = slotPatterns([]int{2})
+	fourSlotPatterns     = slotPatterns([]int{4})
+	twoByTwoSlotPatterns = slotPatterns([]int{2, 2})
+)
+
+// slotPatterns returns every way to give the slots to groups of pieces, taking the given amount of slots each.
+// Every slot holds the index of its group, or -1 if it can have any piece.
+func slotPatterns(counts []int) [][slotCount]int {
+	var patterns [][slotCount]int
+	var pattern [slotCount]int
+	left := append([]int(nil), counts...)
+	var fill func(slot int)
+	fill = func(slot int) {
+		if slot == slotCount {
+			for _, l := range left {
+				if l > 0 {
+					return
+				}
+			}
+			patterns = append(patterns, pattern)
+			return
+		}
+		pattern[slot] = -1
+		fill(slot + 1)
+		for group := range left {
+			if left[group] > 0 {
+				left[group]--
+				pattern[slot] = group
+				fill(slot + 1)
+				left[group]++
+			}
+		}
+	}
+	fill(0)
+	return patterns
+}
+
+// comboStats sets the relevant stats to the elementwise max artifact stats of the builds with the pieces of every group
+// in the slots of one of the patterns, and the max stats of the slot in the others. It returns false if no pattern can be filled.
+// The other stats don't change the damage, so they are left as they are, see buildBounds.otherStats.
+func (b buildBounds) comboStats(stats *statArray, s *slotBounds, groups []*kindSlots, patterns [][slotCount]int) bool {
+	found := false
+	for _, pattern := range patterns {
+		var pieces [slotCount][]float32
+		possible := true
+		for slot, group := range pattern {
+			if group < 0 {
+				pieces[slot] = s.relevantMax[slot]
+			} else if groups[group].has[slot] {
+				pieces[slot] = groups[group].max[slot]
+			} else {
+				possible = false
+				break
+			}
+		}
+		if !possible {
+			continue
+		}
+		for i, stat := range b.relevantPieceStats {
+			var sum float32
+			for _, max := range pieces {
+				sum += max[i]
+			}
+			if !found || sum > stats[stat] {
+				stats[stat] = sum
+			}
+		}
+		found = true
+	}
+	return found
+}
+
+// value returns the damage with the given artifact stats and set effects, or 0 if they can't meet the min stats
+func (b buildBounds) value(artifactStats *statArray, setBonus map[stat]float32, setConversions []conversion) float32 {
+	// the fixed bonus of the character is merged, so its bonus stats are all the stats that don't depend on the artifacts
+	c := b.config.character
+	bonus := make(map[stat]float32, len(c.bonusStats)+len(b.pieceStats)+len(setBonus)+len(flatStats))
+	for stat, v := range c.bonusStats {
+		bonus[stat] = v
+	}
+	for _, stat := range b.pieceStats {
+		bonus[stat] = bonus[stat] + artifactStats[stat]
+	}
+	for stat, v := range setBonus {
+		bonus[stat] = bonus[stat] + v
+	}
+	stats := c.finalStatsOf(bonus, setConversions)
+	if !b.config.constraints.minStatsPossible(stats) {
+		return 0
+	}
+	return b.config.breakdownFor(stats).value(b.config.objective)
+}
+
+// maxStats returns the elementwise max stats of the candidates of every slot, and in how many slots there are pieces of every set
+func (b buildBounds) maxStats(c candidates) ([slotCount]statArray, []int) {
+	var slotMax [slotCount]statArray
+	slotsWithSet := make([]int, len(b.sets))
+	inSlot := make([]bool, len(b.sets))
+	for slot, arts := range c {
+		for i := range inSlot {
+			inSlot[i] = false
+		}
+		for _, art := range arts {
+			piece := b.pieces[art]
+			for _, stat := range b.pieceStats {
+				slotMax[slot][stat] = maxf(slotMax[slot][stat], piece.stats[stat])
+			}
+			if !inSlot[piece.set] {
+				inSlot[piece.set] = true
+				slotsWithSet[piece.set]++
+			}
+		}
+	}
+	return slotMax, slotsWithSet
+}
+
+// sortForSplitting orders the candidates of every slot by main stat, and then by the bound of the builds using them,
+// so splitting a slot keeps similar pieces together and the bounds of every half stay tight
+func (b buildBounds) sortForSplitting(c candidates) {
+	for slot, arts := range c {
+		pieceBounds := make(map[*Artifact]float32, len(arts))
+		for _, art := range arts {
+			single := c
+			single[slot] = []*Artifact{art}
+			pieceBounds[art] = b.bound(single)
+		}
+		sort.SliceStable(arts, func(i, j int) bool {
+			if arts[i].MainStat != arts[j].MainStat {
+				return arts[i].MainStat < arts[j].MainStat
+			}
+			return pieceBounds[arts[i]] > pieceBounds[arts[j]]
+		})
+	}
+}
+
+// leaders returns the first n pieces of every main stat of every slot, the most promising ones once sorted for splitting
+func (c candidates) leaders(n int) candidates {
+	var leaders candidates
+	for slot, arts := range c {
+		run := 0
+		for i, art := range arts {
+			if i == 0 || art.MainStat != arts[i-1].MainStat {
+				run = 0
+			}
+			if run < n {
+				leaders[slot] = append(leaders[slot], art)
+			}
+			run++
+		}
+	}
+	return leaders
+}
+
+// split divides the candidates in groups of builds with lower bounds. If the slot with the most candidates has pieces of
+// different main stats, they are split by main stat. Otherwise, the candidates of the slot and stat with the widest spread
+// of damage are split by that stat, the pieces with the most of it first, so the max stats of every group drop.
+func (b buildBounds) split(c candidates, w *splitWeights) []candidates {
+	slot := 0
+	for s, arts := range c {
+		if len(arts) > len(c[slot]) {
+			slot = s
+		}
+	}
+	arts := c[slot]
+
+	var parts [][]*Artifact
+	if arts[0].MainStat != arts[len(arts)-1].MainStat {
+		start := 0
+		for i := 1; i <= len(arts); i++ {
+			if i == len(arts) || arts[i].MainStat != arts[start].MainStat {
+				parts = append(parts, arts[start:i])
+				start = i
+			}
+		}
+	} else if splitSlot, splitStat, ok := b.widestSpread(c, w); ok {
+		slot, arts = splitSlot, append([]*Artifact(nil), c[splitSlot]...)
+		sort.SliceStable(arts, func(i, j int) bool { return b.pieces[arts[i]].stats[splitStat] > b.pieces[arts[j]].stats[splitStat] })
+		// the cut goes between pieces with different amounts of the stat, as close to the middle as possible
+		cut := len(arts) / 2
+		for cut < len(arts) && b.pieces[arts[cut]].stats[splitStat] == b.pieces[arts[cut-1]].stats[splitStat] {
+			cut++
+		}
+		if cut == len(arts) {
+			for cut = len(arts) / 2; b.pieces[arts[cut]].stats[splitStat] == b.pieces[arts[cut-1]].stats[splitStat]; cut-- {
+			}
+		}
+		parts = [][]*Artifact{arts[:cut], arts[cut:]}
+	} else {
+		parts = [][]*Artifact{arts[:len(arts)/2], arts[len(arts)/2:]}
+	}
+
+	children := make([]candidates, len(parts))
+	for i, part := range parts {
+		children[i] = c
+		children[i][slot] = part
+	}
+	return children
+}
+
+// widestSpread returns the slot and stat whose difference between the candidates with the most and least of it
+// changes the damage the most. It returns false if every slot has pieces with the same stats.
+func (b buildBounds) widestSpread(c candidates, w *splitWeights) (int, stat, bool) {
+	if size := c.size(); w.size == 0 || size <= w.size/reweighRatio {
+		w.stats, w.size = b.weightsAt(c), size
+	}
+	weights := w.stats
+	var bestSlot int
+	var bestStat stat
+	var widest float32 = -1
+	values := make(descending, 0, 64)
+	pieces := make([]*boundPiece, 0, 64)
+	for slot, arts := range c {
+		if len(arts) < 2 {
+			continue
+		}
+		pieces = pieces[:0]
+		for _, art := range arts {
+			pieces = append(pieces, b.pieces[art])
+		}
+		for i, s := range b.relevantPieceStats {
+			values = values[:0]
+			for _, piece := range pieces {
+				values = append(values, piece.relevant[i])
+			}
+			high, lowest := values[0], values[0]
+			for _, v := range values {
+				high, lowest = maxf(high, v), minf(lowest, v)
+			}
+			if high == lowest {
+				continue
+			}
+			// the median, or the highest value under the max if at least half of them have it
+			low := values.nth(len(values) / 2)
+			if low == high {
+				low = lowest
+				for _, v := range values {
+					if v < high {
+						low = maxf(low, v)
+					}
+				}
+			}
+			if drop := (high - low) * weights[s]; drop > widest {
+				bestSlot, bestStat, widest = slot, s, drop
+			}
+		}
+	}
+	return bestSlot, bestStat, widest >= 0
+}
+
+// descending holds values to select from, from highest to lowest, see nth
+type descending []float32
+
+// nth returns the value that would be at index n if the values were sorted from highest to lowest, reordering them
+func (d descending) nth(n int) float32 {
+	left, right := 0, len(d)-1
+	for left < right {
+		pivot := d[(left+right)/2]
+		i, j := left, right
+		for i <= j {
+			for d[i] > pivot {
+				i++
+			}
+			for d[j] < pivot {
+				j--
+			}
+			if i <= j {
+				d[i], d[j] = d[j], d[i]
+				i++
+				j--
+			}
+		}
+		if n <= j {
+			right = j
+		} else if n >= i {
+			left = i
+		} else {
+			return d[n]
+		}
+	}
+	return d[n]
+}
+
+// splitWeights are the weights of the stats used to split groups of candidates, see weightsAt
+type splitWeights struct {
+	stats statArray
+	size  int // of the group they were found for
+}
+
+// reweighRatio is how many times smaller a group of candidates must be than the one its weights were found for to find them again
+const reweighRatio = 16
+
+// weightsAt returns how much the damage bound of the candidates changes with some more of every relevant stat
+func (b buildBounds) weightsAt(c candidates) statArray {
+	s := b.slotBoundsOf(c)
+	sum := b.maxTotal(&s)
+	setBonus, setConversions := b.setBound(s.slotsWithSet)
+	base := b.value(&sum, setBonus, setConversions)
+	var weights statArray
+	for _, stat := range b.relevantPieceStats {
+		sum[stat] += weightProbe
+		weights[stat] = b.value(&sum, setBonus, setConversions) - base
+		sum[stat] -= weightProbe
+	}
+	return weights
+}
+
+// splitInto splits the candidates until there are at least n groups, or every group is a single build
+func (b buildBounds) splitInto(c candidates, n int) []candidates {
+	groups := []candidates{c}
+	for len(groups) < n {
+		largest := 0
+		for i, g := range groups {
+			if g.size() > groups[largest].size() {
+				largest = i
+			}
+		}
+		if groups[largest].size() <= 1 {
+			break
+		}
+		children := b.split(groups[largest], &splitWeights{})
+		groups = append(append(groups[:largest:largest], children...), groups[largest+1:]...)
+	}
+	return groups
+}
+
+// byBound sorts groups of candidates by their bounds, highest first
+type byBound struct {
+	groups []candidates
+	bounds []float32
+}
+
+func (s byBound) Len() int           { return len(s.groups) }
+func (s byBound) Less(i, j int) bool { return s.bounds[i] > s.bounds[j] }
+func (s byBound) Swap(i, j int) {
+	s.groups[i], s.groups[j] = s.groups[j], s.groups[i]
+	s.bounds[i], s.bounds[j] = s.bounds[j], s.bounds[i]
+}
+
+// sharedBest is the best value found by any job, so they can skip the groups that can't reach it
+type sharedBest struct {
+	bits uint32
+}
+
+func (s *sharedBest) get() float32 {
+	return math.Float32frombits(atomic.LoadUint32(&s.bits))
+}
+
+// raise sets the value if it's higher than the current one. Values must not be negative.
+func (s *sharedBest) raise(v float32) {
+	for {
+		old := atomic.LoadUint32(&s.bits)
+		if math.Float32frombits(old) >= v || atomic.CompareAndSwapUint32(&s.bits, old, math.Float32bits(v)) {
+			return
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package genshinartis
+
+// buffProvider is a party member or effect that buffs the character.
+// The buff is calculated from the stats of the provider, so it follows any change to them.
+type buffProvider interface {
+	buff() map[stat]float32
+}
+
+// baseATK returns the base ATK of the character with their weapon
+func (c character) baseATK() float32 {
+	return c.baseAtk + c.weapon.baseAtk
+}
+
+// bennettBurst is the ATK bonus of Fantastic Voyage, based on Bennett's base ATK
+type bennettBurst struct {
+	bennett     character
+	talentLevel int
+	c1          bool // Grand Expectation adds 20% of his base ATK
+}
+
+// NewBennettBurst returns the buff of Bennett's burst at the talent level, or an InvalidValueError if it's out of range
+func NewBennettBurst(bennett character, talentLevel int, c1 bool) (bennettBurst, error) {
+	if err := checkTalentLevel(talentLevel); err != nil {
+		return bennettBurst{}, err
+	}
+	return bennettBurst{bennett: bennett, talentLevel: talentLevel, c1: c1}, nil
+}
+
+func (b bennettBurst) buff() map[stat]float32 {
+	pct := 56 * elementalTalentScaling[b.talentLevel-1]
+	if b.c1 {
+		pct += 20
+	}
+	return map[stat]float32{ATK: b.bennett.baseATK() * pct / 100}
+}
+
+// kazuhaA4 is Poetics of Fuubutsu, the DMG bonus of the swirled element based on Kazuha's EM.
+// Like every A4 passive, it needs Kazuha at ascension phase 4, see characterConversions.
+type kazuhaA4 struct {
+	kazuha  character
+	swirled element
+}
+
+// NewKazuhaA4 returns the buff of Kazuha's A4 for the swirled element, or an InvalidValueError if it can't be swirled
+func NewKazuhaA4(kazuha character, swirled element) (kazuhaA4, error) {
+	switch swirled {
+	case Pyro, Hydro, Electro, Cryo:
+		return kazuhaA4{kazuha: kazuha, swirled: swirled}, nil
+	}
+	return kazuhaA4{}, &InvalidValueError{Field: "swirled element", Value: swirled, Reason: "can't be swirled"}
+}
+
+func (k kazuhaA4) buff() map[stat]float32 {
+	if k.kazuha.ascensionPhase() < 4 {
+		return nil
+	}
+	em := k.kazuha.stats()[ElementalMastery]
+	return map[stat]float32{elementDMGBonusStats[k.swirled]: em * 0.04}
+}
+
+// faruzanBurst is the Anemo DMG bonus of The Wind's Secret Ways and her A4, Perfidious Wind's Bale,
+// which adds Anemo DMG based on Faruzan's base ATK once she reaches ascension phase 4.
+// The Anemo RES shred is a debuff of the enemy instead.
+type faruzanBurst struct {
+	faruzan     character
+	talentLevel int
+}
+
+// NewFaruzanBurst returns the buff of Faruzan's burst at the talent level, or an InvalidValueError if it's out of range
+func NewFaruzanBurst(faruzan character, talentLevel int) (faruzanBurst, error) {
+	if err := checkTalentLevel(talentLevel); err != nil {
+		return faruzanBurst{}, err
+	}
+	return faruzanBurst{faruzan: faruzan, talentLevel: talentLevel}, nil
+}
+
+func (f faruzanBurst) buff() map[stat]float32 {
+	buff := map[stat]float32{AnemoDMG: 18 * elementalTalentScaling[f.talentLevel-1]}
+	if f.faruzan.ascensionPhase() >= 4 {
+		buff[AnemoDMGIncrease] = f.faruzan.baseATK() * 0.32
+	}
+	return buff
+}
+
+// elementalResonance is the effect of having two party members of the element.
+// Conditional effects are assumed to be active, the ones that don't change any stat are not modeled.
+type elementalResonance element
+
+func (r elementalResonance) buff() map[stat]float32 {
+	switch element(r) {
+	case Pyro:
+		return map[stat]float32{ATKP: 25}
+	case Hydro:
+		return map[stat]float32{HPP: 25}
+	case Cryo:
+		return map[stat]float32{CritRate: 15} // against enemies frozen or affected by Cryo
+	case Geo:
+		return map[stat]float32{GlobalDMGBonus: 15} // while shielded
+	case Dendro:
+		return map[stat]float32{ElementalMastery: 50}
+	}
+	return nil
+}
@@ -0,0 +1,232 @@
+package genshinartis
+
+type weaponType int
+
+const (
+	Sword weaponType = iota
+	Claymore
+	Polearm
+	Bow
+	Catalyst
+)
+
+// characterData holds the stats of a character at level 90, the ones at lower levels are derived from them
+type characterData struct {
+	rarity         int
+	element        element
+	weaponType     weaponType
+	hp             float32
+	atk            float32
+	def            float32
+	ascensionStat  stat
+	ascensionValue float32
+}
+
+// Playable characters by GOOD key
+// From https://genshin-impact.fandom.com/wiki/Character/List
+var characters = map[string]characterData{
+	"Albedo":            {5, Geo, Sword, 13226, 251, 876, GeoDMG, 28.8},
+	"Alhaitham":         {5, Dendro, Sword, 13348, 313, 782, DendroDMG, 28.8},
+	"Aloy":              {5, Cryo, Bow, 10899, 234, 676, CryoDMG, 28.8},
+	"AratakiItto":       {5, Geo, Claymore, 12858, 227, 959, CritRate, 19.2},
+	"Arlecchino":        {5, Pyro, Polearm, 13103, 342, 765, CritDmg, 38.4},
+	"Baizhu":            {5, Dendro, Catalyst, 13348, 192, 499, HPP, 28.8},
+	"Chasca":            {5, Anemo, Bow, 9797, 347, 614, CritRate, 19.2},
+	"Chiori":            {5, Geo, Sword, 11526, 324, 953, CritRate, 19.2},
+	"Citlali":           {5, Cryo, Catalyst, 11633, 127, 763, ElementalMastery, 115.2},
+	"Clorinde":          {5, Electro, Sword, 12956, 337, 784, CritRate, 19.2},
+	"Cyno":              {5, Electro, Polearm, 12491, 318, 859, CritDmg, 38.4},
+	"Dehya":             {5, Pyro, Claymore, 15675, 265, 627, HPP, 28.8},
+	"Diluc":             {5, Pyro, Claymore, 12981, 335, 784, CritRate, 19.2},
+	"Emilie":            {5, Dendro, Polearm, 13568, 334, 730, CritDmg, 38.4},
+	"Eula":              {5, Cryo, Claymore, 13226, 342, 751, CritDmg, 38.4},
+	"Furina":            {5, Hydro, Sword, 15307, 244, 696, CritRate, 19.2},
+	"Ganyu":             {5, Cryo, Bow, 9797, 335, 630, CritDmg, 38.4},
+	"HuTao":             {5, Pyro, Polearm, 15552, 106, 876, CritDmg, 38.4},
+	"Jean":              {5, Anemo, Sword, 14695, 239, 769, HealingBonus, 22.2},
+	"KaedeharaKazuha":   {5, Anemo, Sword, 13348, 297, 807, ElementalMastery, 115.2},
+	"KamisatoAyaka":     {5, Cryo, Sword, 12858, 342, 784, CritDmg, 38.4},
+	"KamisatoAyato":     {5, Hydro, Sword, 13715, 299, 769, CritDmg, 38.4},
+	"Keqing":            {5, Electro, Sword, 13103, 323, 799, CritDmg, 38.4},
+	"Kinich":            {5, Dendro, Claymore, 12706, 331, 792, CritDmg, 38.4},
+	"Klee":              {5, Pyro, Catalyst, 10287, 311, 615, PyroDMG, 28.8},
+	"Lyney":             {5, Pyro, Bow, 11021, 318, 539, CritRate, 19.2},
+	"Mavuika":           {5, Pyro, Claymore, 12552, 359, 791, CritDmg, 38.4},
+	"Mona":              {5, Hydro, Catalyst, 10409, 287, 653, EnergyRecharge, 32},
+	"Mualani":           {5, Hydro, Catalyst, 15185, 181, 571, CritRate, 19.2},
+	"Nahida":            {5, Dendro, Catalyst, 10360, 299, 632, ElementalMastery, 115.2},
+	"Navia":             {5, Geo, Claymore, 12650, 351, 793, CritDmg, 38.4},
+	"Neuvillette":       {5, Hydro, Catalyst, 14695, 208, 576, CritDmg, 38.4},
+	"Nilou":             {5, Hydro, Sword, 15185, 230, 728, HPP, 28.8},
+	"Qiqi":              {5, Cryo, Sword, 12368, 287, 922, HealingBonus, 22.2},
+	"RaidenShogun":      {5, Electro, Polearm, 12907, 337, 789, EnergyRecharge, 32},
+	"SangonomiyaKokomi": {5, Hydro, Catalyst, 13471, 234, 657, HydroDMG, 28.8},
+	"Shenhe":            {5, Cryo, Polearm, 12993, 304, 830, ATKP, 28.8},
+	"Sigewinne":         {5, Hydro, Bow, 13348, 202, 501, HPP, 28.8},
+	"Tartaglia":         {5, Hydro, Bow, 13103, 301, 815, HydroDMG, 28.8},
+	"Tighnari":          {5, Dendro, Bow, 10850, 268, 630, DendroDMG, 28.8},
+	"TravelerAnemo":     {5, Anemo, Sword, 10875, 216, 683, ATKP, 24},
+	"TravelerGeo":       {5, Geo, Sword, 10875, 216, 683, ATKP, 24},
+	"TravelerElectro":   {5, Electro, Sword, 10875, 216, 683, ATKP, 24},
+	"TravelerDendro":    {5, Dendro, Sword, 10875, 216, 683, ATKP, 24},
+	"TravelerHydro":     {5, Hydro, Sword, 10875, 216, 683, ATKP, 24},
+	"TravelerPyro":      {5, Pyro, Sword, 10875, 216, 683, ATKP, 24},
+	"Venti":             {5, Anemo, Bow, 10531, 263, 669, EnergyRecharge, 32},
+	"Wanderer":          {5, Anemo, Catalyst, 10164, 328, 607, CritRate, 19.2},
+	"Wriothesley":       {5, Cryo, Catalyst, 13592, 311, 763, CritDmg, 38.4},
+	"Xianyun":           {5, Anemo, Catalyst, 10409, 335, 573, ATKP, 28.8},
+	"Xiao":              {5, Anemo, Polearm, 12736, 349, 799, CritRate, 19.2},
+	"Xilonen":           {5, Geo, Sword, 12405, 275, 930, DEFP, 36},
+	"YaeMiko":           {5, Electro, Catalyst, 10372, 340, 569, CritRate, 19.2},
+	"Yelan":             {5, Hydro, Bow, 14450, 244, 548, CritRate, 19.2},
+	"Yoimiya":           {5, Pyro, Bow, 10164, 323, 615, CritRate, 19.2},
+	"Zhongli":           {5, Geo, Polearm, 14695, 251, 738, GeoDMG, 28.8},
+	"Amber":             {4, Pyro, Bow, 9461, 223, 601, ATKP, 24},
+	"Barbara":           {4, Hydro, Catalyst, 9787, 159, 669, HPP, 24},
+	"Beidou":            {4, Electro, Claymore, 13050, 225, 648, ElectroDMG, 24},
+	"Bennett":           {4, Pyro, Sword, 12397, 191, 771, EnergyRecharge, 26.7},
+	"Candace":           {4, Hydro, Polearm, 10875, 212, 682, HPP, 24},
+	"Charlotte":         {4, Cryo, Catalyst, 10766, 181, 546, ATKP, 24},
+	"Chevreuse":         {4, Pyro, Polearm, 11962, 193, 605, HPP, 24},
+	"Chongyun":          {4, Cryo, Claymore, 10984, 223, 648, ATKP, 24},
+	"Collei":            {4, Dendro, Bow, 9787, 200, 600, ATKP, 24},
+	"Diona":             {4, Cryo, Bow, 9570, 212, 601, CryoDMG, 24},
+	"Dori":              {4, Electro, Claymore, 12397, 223, 723, HPP, 24},
+	"Faruzan":           {4, Anemo, Bow, 9570, 196, 628, AnemoDMG, 24},
+	"Fischl":            {4, Electro, Bow, 9189, 244, 594, ATKP, 24},
+	"Freminet":          {4, Cryo, Claymore, 12071, 254, 594, ATKP, 24},
+	"Gaming":            {4, Pyro, Claymore, 11419, 301, 703, ATKP, 24},
+	"Gorou":             {4, Geo, Bow, 9570, 183, 648, GeoDMG, 24},
+	"Kachina":           {4, Geo, Polearm, 11779, 189, 766, GeoDMG, 24},
+	"Kaeya":             {4, Cryo, Sword, 11636, 223, 792, EnergyRecharge, 26.7},
+	"Kaveh":             {4, Dendro, Claymore, 11962, 234, 751, ElementalMastery, 96},
+	"Kirara":            {4, Dendro, Sword, 12180, 200, 546, HPP, 24},
+	"KujouSara":         {4, Electro, Bow, 9570, 195, 628, ATKP, 24},
+	"KukiShinobu":       {4, Electro, Sword, 12289, 212, 751, HPP, 24},
+	"LanYan":            {4, Anemo, Catalyst, 9244, 271, 517, ATKP, 24},
+	"Layla":             {4, Cryo, Sword, 11962, 217, 678, HPP, 24},
+	"Lisa":              {4, Electro, Catalyst, 9570, 232, 573, ElementalMastery, 96},
+	"Lynette":           {4, Anemo, Sword, 12397, 232, 712, AnemoDMG, 24},
+	"Mika":              {4, Cryo, Polearm, 12506, 223, 713, HPP, 24},
+	"Ningguang":         {4, Geo, Catalyst, 9787, 212, 573, GeoDMG, 24},
+	"Noelle":            {4, Geo, Claymore, 12071, 191, 799, DEFP, 30},
+	"Ororon":            {4, Electro, Bow, 9787, 244, 593, ATKP, 24},
+	"Razor":             {4, Electro, Claymore, 11962, 234, 751, PhysDMG, 30},
+	"Rosaria":           {4, Cryo, Polearm, 12289, 240, 710, ATKP, 24},
+	"Sayu":              {4, Anemo, Claymore, 11854, 244, 745, ElementalMastery, 96},
+	"Sethos":            {4, Electro, Bow, 9787, 227, 561, ElementalMastery, 96},
+	"ShikanoinHeizou":   {4, Anemo, Catalyst, 10657, 225, 683, AnemoDMG, 24},
+	"Sucrose":           {4, Anemo, Catalyst, 9244, 170, 703, AnemoDMG, 24},
+	"Thoma":             {4, Pyro, Polearm, 10331, 202, 751, ATKP, 24},
+	"Xiangling":         {4, Pyro, Polearm, 10875, 225, 669, ElementalMastery, 96},
+	"Xingqiu":           {4, Hydro, Sword, 10222, 202, 758, ATKP, 24},
+	"Xinyan":            {4, Pyro, Claymore, 11201, 249, 799, ATKP, 24},
+	"Yanfei":            {4, Pyro, Catalyst, 9352, 240, 587, PyroDMG, 24},
+	"Yaoyao":            {4, Dendro, Polearm, 12289, 212, 751, HPP, 24},
+	"YunJin":            {4, Geo, Polearm, 10657, 191, 734, EnergyRecharge, 26.7},
+}
+
+// Passive talents calculated from other stats of the character, by GOOD key, with the ascension phase that unlocks them
+var characterConversions = map[string]struct {
+	ascension  int
+	conversion conversion
+}{
+	// Enlightened One: 0.4% Electro DMG bonus for every 1% ER over 100%
+	"RaidenShogun": {4, func(s map[stat]float32) map[stat]float32 {
+		return map[stat]float32{ElectroDMG: maxf(0, s[EnergyRecharge]-100) * 0.4}
+	}},
+	// Awakening Elucidated: Tri-Karma Purification DMG and CRIT Rate for every point of EM over 200
+	"Nahida": {4, func(s map[stat]float32) map[stat]float32 {
+		em := maxf(0, s[ElementalMastery]-200)
+		return map[stat]float32{SkillDMG: minf(80, em*0.1), SkillCritRate: minf(24, em*0.03)}
+	}},
+}
+
+// Base stats grow with the level following the curves of the character's rarity, plus a bonus on every ascension.
+// Without loaded curves (see LoadGrowthCurves), they grow linearly from level 1 to 90. That's exact at level 90
+// but up to ~2% off below it (Xiao has 2572 base HP at level 20, the linear growth gives ~2616).
+var characterGrowth = map[int]struct {
+	level1    float32 // fraction of the level 90 value at level 1, ignoring ascensions
+	ascension float32 // fraction of the level 90 value given by ascensions
+	hpCurve   string  // used by HP and DEF
+	atkCurve  string
+}{
+	4: {0.12, 0.30, "GROW_CURVE_HP_S4", "GROW_CURVE_ATTACK_S4"},
+	5: {0.115, 0.324, "GROW_CURVE_HP_S5", "GROW_CURVE_ATTACK_S5"},
+}
+
+// Fraction of the ascension bonus unlocked on every ascension phase
+var ascensionBaseStatShare = [7]float32{0, 38.0 / 182, 65.0 / 182, 101.0 / 182, 128.0 / 182, 155.0 / 182, 1}
+var ascensionStatShare = [7]float32{0, 0, 0.25, 0.5, 0.5, 0.75, 1}
+
+// Max level of every ascension phase
+var ascensionMaxLevels = [7]int{20, 40, 50, 60, 70, 80, 90}
+
+// ascensionForLevel returns the lowest ascension phase that allows the given level
+func ascensionForLevel(level int) int {
+	for ascension, maxLevel := range ascensionMaxLevels {
+		if level <= maxLevel {
+			return ascension
+		}
+	}
+	return len(ascensionMaxLevels) - 1
+}
+
+// ascensionPhase returns the ascension phase of the character.
+// Characters built by hand may not set it, then it's the lowest one that allows their level.
+func (c character) ascensionPhase() int {
+	if phase := ascensionForLevel(c.level); phase > c.ascension {
+		return phase
+	}
+	return c.ascension
+}
+
+// CharacterByKey returns the character with the GOOD key, at the given level and the lowest ascension that allows it
+func CharacterByKey(key string, level int) (character, error) {
+	return CharacterByKeyAndAscension(key, level, ascensionForLevel(level))
+}
+
+// CharacterFromGOOD returns the character with the level and ascension of an imported GOOD character
+func CharacterFromGOOD(c GOODCharacter) (character, error) {
+	return CharacterByKeyAndAscension(c.Key, c.Level, c.Ascension)
+}
+
+func CharacterByKeyAndAscension(key string, level, ascension int) (character, error) {
+	data, ok := characters[key]
+	if !ok {
+		return character{}, &UnknownKeyError{Field: "character key", Key: key}
+	}
+	if level < 1 || level > ascensionMaxLevels[len(ascensionMaxLevels)-1] {
+		return character{}, &InvalidValueError{Field: "level", Value: level, Reason: "out of range"}
+	}
+	if ascension < ascensionForLevel(level) || ascension >= len(ascensionMaxLevels) ||
+		(ascension > 0 && level < ascensionMaxLevels[ascension-1]) {
+		return character{}, &InvalidValueError{Field: "ascension", Value: ascension, Reason: "impossible at the given level"}
+	}
+
+	growth := characterGrowth[data.rarity]
+	baseStatShare := func(curveType string) float32 {
+		levelShare, ok := curveShare(curveType, level)
+		if !ok {
+			levelShare = growth.level1 + (1-growth.level1)*float32(level-1)/89
+		}
+		return (1-growth.ascension)*levelShare + growth.ascension*ascensionBaseStatShare[ascension]
+	}
+	var conversions []conversion
+	if passive, ok := characterConversions[key]; ok && ascension >= passive.ascension {
+		conversions = append(conversions, passive.conversion)
+	}
+
+	return character{
+		key:         key,
+		level:       level,
+		ascension:   ascension,
+		element:     data.element,
+		weaponType:  data.weaponType,
+		baseHP:      data.hp * baseStatShare(growth.hpCurve),
+		baseAtk:     data.atk * baseStatShare(growth.atkCurve),
+		baseDef:     data.def * baseStatShare(growth.hpCurve),
+		bonusStats:  map[stat]float32{data.ascensionStat: data.ascensionValue * ascensionStatShare[ascension]},
+		conversions: conversions,
+	}, nil
+}
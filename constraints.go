@@ -0,0 +1,210 @@
+package genshinartis
+
+// setRequirement is the amount of pieces of every set a build needs, like 4 of a set or 2 of two sets.
+// An empty requirement is met by any build, like a rainbow one.
+type setRequirement map[artifactSet]int
+
+func fourPieceOf(set artifactSet) setRequirement {
+	return setRequirement{set: 4}
+}
+
+func twoPieceOf(setA, setB artifactSet) setRequirement {
+	return setRequirement{setA: 2, setB: 2}
+}
+
+// mainStatRules are the allowed main stats of every slot, any for the slots without them
+type mainStatRules map[artifactSlot][]stat
+
+// allows returns true if the main stat of the artifact is allowed in its slot
+func (r mainStatRules) allows(art *Artifact) bool {
+	allowed, ok := r[art.Slot]
+	if !ok {
+		return true
+	}
+	for _, mainStat := range allowed {
+		if art.MainStat == mainStat {
+			return true
+		}
+	}
+	return false
+}
+
+// buildConstraints are the requirements of the builds the optimizer looks for. The zero value allows any build.
+type buildConstraints struct {
+	sets      []setRequirement           // the build must meet one of them, any build if there are none
+	minStats  map[stat]float32           // of the final stats of the character
+	maxStats  map[stat]float32           // of the final stats of the character
+	mainStats mainStatRules              // see findBestMainStats to try every combination instead
+	locked    map[artifactSlot]*Artifact // only the locked piece of the slot is used, it must be one of the artifacts searched
+	excluded  map[*Artifact]bool
+}
+
+// allowsPiece returns true if the artifact can be part of a build
+func (c buildConstraints) allowsPiece(art *Artifact) bool {
+	if c.excluded[art] {
+		return false
+	}
+	if locked, ok := c.locked[art.Slot]; ok && locked != art {
+		return false
+	}
+	return c.mainStats.allows(art)
+}
+
+// validateLocked returns an InvalidValueError if a locked piece isn't one of the searched artifacts of its slot,
+// as no build could be made then
+func (c buildConstraints) validateLocked(searched []*Artifact) error {
+	for slot := SlotFlower; slot <= SlotCirclet; slot++ {
+		locked, ok := c.locked[slot]
+		if !ok {
+			continue
+		}
+		if locked == nil || locked.Slot != slot {
+			return &InvalidValueError{Field: "locked artifact", Value: slot, Reason: "not an artifact of the slot"}
+		}
+		found := false
+		for _, art := range searched {
+			if art == locked {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return &InvalidValueError{Field: "locked artifact", Value: slot, Reason: "not among the searched artifacts"}
+		}
+	}
+	return nil
+}
+
+// filterPieces returns the artifacts that can be part of a build, keeping their order
+func (c buildConstraints) filterPieces(artifacts []*Artifact) []*Artifact {
+	filtered := make([]*Artifact, 0, len(artifacts))
+	for _, art := range artifacts {
+		if c.allowsPiece(art) {
+			filtered = append(filtered, art)
+		}
+	}
+	return filtered
+}
+
+// allowsSets returns true if the build meets one of the set requirements
+func (c buildConstraints) allowsSets(build map[artifactSlot]*Artifact) bool {
+	if len(c.sets) == 0 {
+		return true
+	}
+	counts := make(map[artifactSet]int, slotCount)
+	for _, art := range build {
+		counts[art.Set]++
+	}
+	for _, req := range c.sets {
+		met := true
+		for set, count := range req {
+			if counts[set] < count {
+				met = false
+				break
+			}
+		}
+		if met {
+			return true
+		}
+	}
+	return false
+}
+
+// setsPossible returns true if any build made from the candidates can meet one of the set requirements
+func (c buildConstraints) setsPossible(cands candidates) bool {
+	if len(c.sets) == 0 {
+		return true
+	}
+	return c.setsPossibleFrom(slotSetsOf(cands))
+}
+
+// slotSetsOf returns the sets of the candidates of every slot
+func slotSetsOf(cands candidates) [slotCount]map[artifactSet]bool {
+	var slotSets [slotCount]map[artifactSet]bool
+	for slot, arts := range cands {
+		slotSets[slot] = map[artifactSet]bool{}
+		for _, art := range arts {
+			slotSets[slot][art.Set] = true
+		}
+	}
+	return slotSets
+}
+
+// setsPossibleFrom returns true if any build made from pieces of the given sets of every slot can meet one of the set requirements
+func (c buildConstraints) setsPossibleFrom(slotSets [slotCount]map[artifactSet]bool) bool {
+	return c.setsPossibleWith(func(slot int, set artifactSet) bool { return slotSets[slot][set] })
+}
+
+// setsPossibleWith is setsPossibleFrom with a function that tells if a slot has pieces of a set
+func (c buildConstraints) setsPossibleWith(slotHas func(slot int, set artifactSet) bool) bool {
+	if len(c.sets) == 0 {
+		return true
+	}
+	for _, req := range c.sets {
+		missing := make([]setCount, 0, len(req))
+		for set, count := range req {
+			missing = append(missing, setCount{set, count})
+		}
+		if assignable(0, slotHas, missing) {
+			return true
+		}
+	}
+	return false
+}
+
+// setCount is an amount of pieces of a set
+type setCount struct {
+	set   artifactSet
+	count int
+}
+
+// assignable returns true if every missing piece can be taken from a different slot, from the given one on, that has pieces of its set
+func assignable(slot int, slotHas func(slot int, set artifactSet) bool, missing []setCount) bool {
+	left := 0
+	for _, m := range missing {
+		left += m.count
+	}
+	if left == 0 {
+		return true
+	}
+	if left > slotCount-slot {
+		return false
+	}
+	for i := range missing {
+		if missing[i].count > 0 && slotHas(slot, missing[i].set) {
+			missing[i].count--
+			ok := assignable(slot+1, slotHas, missing)
+			missing[i].count++
+			if ok {
+				return true
+			}
+		}
+	}
+	return assignable(slot+1, slotHas, missing)
+}
+
+// allowsStats returns true if the final stats are within the min and max stats
+func (c buildConstraints) allowsStats(stats map[stat]float32) bool {
+	for stat, min := range c.minStats {
+		if stats[stat] < min {
+			return false
+		}
+	}
+	for stat, max := range c.maxStats {
+		if stats[stat] > max {
+			return false
+		}
+	}
+	return true
+}
+
+// minStatsPossible returns true if stats that no build of a group can exceed reach the min stats.
+// The max stats can't be checked the same way, as the builds can have lower stats.
+func (c buildConstraints) minStatsPossible(upperStats map[stat]float32) bool {
+	for stat, min := range c.minStats {
+		if upperStats[stat] < min {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,67 @@
+package genshinartis
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// growthCurve holds the multiplier of a stat at every level from 1 to 90, relative to its level 1 value
+type growthCurve [90]float32
+
+// Growth curves of the game by curve type, like GROW_CURVE_HP_S5. Loaded with LoadGrowthCurves.
+var growthCurves = map[string]growthCurve{}
+
+// LoadGrowthCurves loads the growth curves from one of the game's curve config files, like
+// AvatarCurveExcelConfigData.json or WeaponCurveExcelConfigData.json.
+// Characters and weapons created afterwards use them instead of the linear approximations,
+// so it should be called before creating any of them.
+func LoadGrowthCurves(r io.Reader) error {
+	var levels []struct {
+		Level      int `json:"level"`
+		CurveInfos []struct {
+			Type  string  `json:"type"`
+			Value float32 `json:"value"`
+		} `json:"curveInfos"`
+	}
+	if err := json.NewDecoder(r).Decode(&levels); err != nil {
+		return err
+	}
+
+	loaded := map[string]growthCurve{}
+	seen := map[string]int{}
+	for _, level := range levels {
+		if level.Level < 1 || level.Level > len(growthCurve{}) {
+			continue
+		}
+		for _, info := range level.CurveInfos {
+			if info.Value <= 0 {
+				return &InvalidValueError{Field: info.Type, Value: info.Value, Reason: fmt.Sprintf("not a multiplier at level %d", level.Level)}
+			}
+			curve := loaded[info.Type]
+			curve[level.Level-1] = info.Value
+			loaded[info.Type] = curve
+			seen[info.Type]++
+		}
+	}
+	for curveType, levels := range seen {
+		if levels != len(growthCurve{}) {
+			return &InvalidValueError{Field: curveType, Value: levels, Reason: "expected a value for every level from 1 to 90"}
+		}
+	}
+
+	for curveType, curve := range loaded {
+		growthCurves[curveType] = curve
+	}
+	return nil
+}
+
+// curveShare returns the value of a stat at the level as a fraction of its level 90 value,
+// or false if the curve isn't loaded
+func curveShare(curveType string, level int) (float32, bool) {
+	curve, ok := growthCurves[curveType]
+	if !ok {
+		return 0, false
+	}
+	return curve[level-1] / curve[len(curve)-1], true
+}
@@ -0,0 +1,203 @@
+package genshinartis
+
+/**
+Pieces that can't be part of the best builds, skipped before the search so the bounds have fewer pieces to go through.
+A piece is dominated by another one of the same slot, main stat and set if the other one has at least as much of every stat
+that matters, and no more of the stats that raise a final stat with a max. As the damage and final stats never decrease when
+a stat increases, swapping the dominated piece for any of its dominators gives builds at least as good that meet the same
+constraints, so a piece dominated by n others can't be needed for the n best builds.
+**/
+
+// dominanceProbes are the amounts every stat is raised by to find if it matters, see statProbes
+var dominanceProbes = []float32{10, 1000}
+
+// statProbes raise every stat the pieces and sets can give on its own to find what it changes:
+// from no artifacts at all, from no artifacts but the conversions of every set on its own, and from the max stats and set
+// effects the candidates can give.
+// Only 4 piece sets have conversions, so a build has the ones of a single set at most. Its stats only raise the inputs
+// of the conversions, so a conversion with a cap, like the Burst DMG of Emblem, is the furthest from it without artifacts.
+type statProbes struct {
+	b      buildBounds
+	stats  []stat
+	points []probePoint
+}
+
+type probePoint struct {
+	artifactStats, setBonus map[stat]float32
+	setConversions          []conversion
+}
+
+func (b buildBounds) statProbes(bySlot candidates) statProbes {
+	candidateStats := map[stat]bool{}
+	for _, s := range b.pieceStats {
+		candidateStats[s] = true
+	}
+	for _, set := range b.sets {
+		for s := range set.fourPiece {
+			candidateStats[s] = true
+		}
+	}
+	p := statProbes{b: b}
+	for s := range candidateStats {
+		p.stats = append(p.stats, s)
+	}
+
+	slotMax, slotsWithSet := b.maxStats(bySlot)
+	setBonus, setConversions := b.setBound(slotsWithSet)
+	top := map[stat]float32{}
+	for _, max := range slotMax {
+		for _, s := range b.pieceStats {
+			top[s] += max[s]
+		}
+	}
+	p.points = []probePoint{{map[stat]float32{}, map[stat]float32{}, nil}}
+	for _, set := range b.sets {
+		if len(set.conversions) > 0 {
+			p.points = append(p.points, probePoint{map[stat]float32{}, map[stat]float32{}, set.conversions})
+		}
+	}
+	p.points = append(p.points, probePoint{top, setBonus, setConversions})
+	return p
+}
+
+// changing returns the stats whose increase changes the result of f, given the final stats of the character
+func (p statProbes) changing(f func(finalStats map[stat]float32) float32) map[stat]bool {
+	changing := map[stat]bool{}
+	for _, point := range p.points {
+		base := f(p.b.config.character.finalStatsWith(point.artifactStats, point.setBonus, point.setConversions))
+		for _, s := range p.stats {
+			for _, probe := range dominanceProbes {
+				raised := copyStats(point.artifactStats)
+				raised[s] += probe
+				if f(p.b.config.character.finalStatsWith(raised, point.setBonus, point.setConversions)) != base {
+					changing[s] = true
+				}
+			}
+		}
+	}
+	return changing
+}
+
+// relevantStats returns the stats of the pieces and sets that can change the damage or any final stat with a min,
+// like ATK% for a min ATK
+func (b buildBounds) relevantStats(bySlot candidates) map[stat]bool {
+	p := b.statProbes(bySlot)
+	relevant := p.changing(func(stats map[stat]float32) float32 {
+		return b.config.breakdownFor(stats).value(b.config.objective)
+	})
+	for s := range b.config.constraints.minStats {
+		for changed := range p.changing(func(stats map[stat]float32) float32 { return stats[s] }) {
+			relevant[changed] = true
+		}
+	}
+	return relevant
+}
+
+// cappedStats returns the stats of the pieces and sets that can change any final stat with a max
+func (b buildBounds) cappedStats(bySlot candidates) map[stat]bool {
+	p := b.statProbes(bySlot)
+	capped := map[stat]bool{}
+	for s := range b.config.constraints.maxStats {
+		for changed := range p.changing(func(stats map[stat]float32) float32 { return stats[s] }) {
+			capped[changed] = true
+		}
+	}
+	return capped
+}
+
+// neutralSets returns the sets whose effects don't change any relevant or capped stat, and that no set requirement names.
+// Their pieces can be swapped for each other without changing the damage, the constrained stats or the sets a build meets.
+func (b buildBounds) neutralSets(relevant, capped map[stat]bool) []bool {
+	named := map[artifactSet]bool{}
+	for _, req := range b.config.constraints.sets {
+		for set := range req {
+			named[set] = true
+		}
+	}
+	neutral := make([]bool, len(b.sets))
+	for i, set := range b.sets {
+		neutral[i] = len(set.conversions) == 0 && !named[set.set]
+		for s, v := range set.fourPiece {
+			if v != 0 && (relevant[s] || capped[s]) {
+				neutral[i] = false
+			}
+		}
+	}
+	return neutral
+}
+
+// dominates returns true if piece a has at least as much of every relevant stat as the other piece, and at most as much
+// of every capped stat, so it must have the same amount of the stats that are both.
+// Between pieces with the same stats, the first one dominates.
+func (b buildBounds) dominates(a, other *boundPiece, aFirst bool, relevant []stat, capped []stat) bool {
+	strictly := false
+	for _, s := range relevant {
+		if a.stats[s] < other.stats[s] {
+			return false
+		}
+		if a.stats[s] > other.stats[s] {
+			strictly = true
+		}
+	}
+	for _, s := range capped {
+		if a.stats[s] > other.stats[s] {
+			return false
+		}
+		if a.stats[s] < other.stats[s] {
+			strictly = true
+		}
+	}
+	return strictly || aFirst
+}
+
+// withoutDominated returns the candidates without the pieces dominated by n others, keeping their order.
+// It can only be used without a build filter, as it could reject the builds of the dominators.
+func (b buildBounds) withoutDominated(bySlot candidates, n int) candidates {
+	cappedSet := b.cappedStats(bySlot)
+	neutral := b.neutralSets(b.relevant, cappedSet)
+	var relevant, capped []stat
+	for s := range b.relevant {
+		relevant = append(relevant, s)
+	}
+	for s := range cappedSet {
+		capped = append(capped, s)
+	}
+
+	type group struct {
+		set      int // -1 for the neutral sets
+		mainStat stat
+	}
+	var kept candidates
+	for slot, arts := range bySlot {
+		groups := map[group][]int{}
+		for i, art := range arts {
+			piece := b.pieces[art]
+			g := group{piece.set, art.MainStat}
+			if neutral[piece.set] {
+				g.set = -1
+			}
+			groups[g] = append(groups[g], i)
+		}
+		keep := make([]bool, len(arts))
+		for _, indexes := range groups {
+			for _, i := range indexes {
+				dominators := 0
+				for _, j := range indexes {
+					if j != i && b.dominates(b.pieces[arts[j]], b.pieces[arts[i]], j < i, relevant, capped) {
+						dominators++
+						if dominators >= n {
+							break
+						}
+					}
+				}
+				keep[i] = dominators < n
+			}
+		}
+		for i, art := range arts {
+			if keep[i] {
+				kept[slot] = append(kept[slot], art)
+			}
+		}
+	}
+	return kept
+}
@@ -0,0 +1,40 @@
+package genshinartis
+
+type enemy struct {
+	level        int
+	resistances  map[element]float32 // base RES, in %
+	resShred     map[element]float32 // RES reduction from the team, in %
+	defReduction float32             // in %
+	defIgnore    float32             // in %
+}
+
+// standardEnemy is a level 90 enemy with 10% RES to everything, like most hilichurls
+var standardEnemy = enemy{level: 90, resistances: uniformResistances(10)}
+
+func uniformResistances(res float32) map[element]float32 {
+	resistances := map[element]float32{}
+	for e := Physical; e <= Geo; e++ {
+		resistances[e] = res
+	}
+	return resistances
+}
+
+// defMultiplier returns the fraction of damage an attacker of the given level deals through the enemy's DEF
+func (e enemy) defMultiplier(characterLevel int) float32 {
+	attacker := float32(characterLevel + 100)
+	defense := float32(e.level+100) * (1 - e.defReduction/100) * (1 - e.defIgnore/100)
+	return attacker / (attacker + defense)
+}
+
+// resMultiplier returns the fraction of damage of the given element the enemy takes after its RES
+func (e enemy) resMultiplier(el element) float32 {
+	res := (e.resistances[el] - e.resShred[el]) / 100
+	switch {
+	case res < 0:
+		return 1 - res/2
+	case res < 0.75:
+		return 1 - res
+	default:
+		return 1 / (4*res + 1)
+	}
+}
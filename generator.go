@@ -0,0 +1,112 @@
+package genshinartis
+
+import "math/rand"
+
+// Generator generates artifacts using its own source of randomness,
+// so the same seed always generates the same stream of artifacts
+type Generator struct {
+	rng RNG
+}
+
+var defaultGenerator = NewGenerator(globalRNG{})
+
+func NewGenerator(rng RNG) *Generator {
+	return &Generator{rng: rng}
+}
+
+func NewSeededGenerator(seed int64) *Generator {
+	return NewGenerator(rand.New(rand.NewSource(seed)))
+}
+
+// LevelUp works like Artifact.LevelUp, using the generator's source of randomness
+func (g *Generator) LevelUp(a *Artifact) {
+	a.levelUp(g.rng)
+}
+
+// LevelTo works like Artifact.LevelTo, using the generator's source of randomness
+func (g *Generator) LevelTo(a *Artifact, level int) {
+	a.levelTo(g.rng, level)
+}
+
+func (g *Generator) RandomArtifact(base4Chance float32) *Artifact {
+	return g.RandomArtifactWithRarity(MaxRarity, base4Chance)
+}
+
+func (g *Generator) RandomArtifactOfSlot(slot artifactSlot, base4Chance float32) *Artifact {
+	return g.RandomArtifactOfSlotWithRarity(slot, MaxRarity, base4Chance)
+}
+
+func (g *Generator) RandomArtifactOfSet(set string, base4Chance float32) *Artifact {
+	return g.RandomArtifactOfSetWithRarity(set, MaxRarity, base4Chance)
+}
+
+func (g *Generator) RandomArtifactFromDomain(setA, setB string) *Artifact {
+	return g.RandomArtifactFromDomainWithRarity(setA, setB, MaxRarity)
+}
+
+// RandomUnleveledArtifactOfSet works like RandomArtifactOfSet, but the artifact is returned at +0
+func (g *Generator) RandomUnleveledArtifactOfSet(set string, base4Chance float32) *Artifact {
+	return g.RandomUnleveledArtifactOfSetWithRarity(set, MaxRarity, base4Chance)
+}
+
+// RandomUnleveledArtifactFromDomain works like RandomArtifactFromDomain, but the artifact is returned at +0
+func (g *Generator) RandomUnleveledArtifactFromDomain(setA, setB string) *Artifact {
+	return g.RandomUnleveledArtifactFromDomainWithRarity(setA, setB, MaxRarity)
+}
+
+// The WithRarity variants accept rarities from MinGeneratedRarity to MaxRarity and panic on any other one,
+// or on a known set that doesn't drop with the rarity
+
+func (g *Generator) RandomArtifactWithRarity(rarity int, base4Chance float32) *Artifact {
+	var artifact Artifact
+	artifact.Rarity = generatedRarity(rarity)
+	artifact.randomizeSet(g.rng, artifactSetsOfRarity(artifact.Rarity)...)
+	artifact.randomizeSlot(g.rng)
+	artifact.ranzomizeMainStat(g.rng)
+	artifact.randomizeSubstats(g.rng, base4Chance)
+	artifact.levelTo(g.rng, maxLevel(artifact.Rarity))
+	return &artifact
+}
+
+func (g *Generator) RandomArtifactOfSlotWithRarity(slot artifactSlot, rarity int, base4Chance float32) *Artifact {
+	var artifact Artifact
+	artifact.Rarity = generatedRarity(rarity)
+	artifact.randomizeSet(g.rng, artifactSetsOfRarity(artifact.Rarity)...)
+	artifact.Slot = slot
+	artifact.ranzomizeMainStat(g.rng)
+	artifact.randomizeSubstats(g.rng, base4Chance)
+	artifact.levelTo(g.rng, maxLevel(artifact.Rarity))
+	return &artifact
+}
+
+func (g *Generator) RandomArtifactOfSetWithRarity(set string, rarity int, base4Chance float32) *Artifact {
+	artifact := g.RandomUnleveledArtifactOfSetWithRarity(set, rarity, base4Chance)
+	artifact.levelTo(g.rng, maxLevel(artifact.Rarity))
+	return artifact
+}
+
+func (g *Generator) RandomArtifactFromDomainWithRarity(setA, setB string, rarity int) *Artifact {
+	artifact := g.RandomUnleveledArtifactFromDomainWithRarity(setA, setB, rarity)
+	artifact.levelTo(g.rng, maxLevel(artifact.Rarity))
+	return artifact
+}
+
+func (g *Generator) RandomUnleveledArtifactOfSetWithRarity(set string, rarity int, base4Chance float32) *Artifact {
+	var artifact Artifact
+	artifact.Rarity = generatedRarity(rarity)
+	artifact.Set = setOfRarity(set, artifact.Rarity)
+	artifact.randomizeSlot(g.rng)
+	artifact.ranzomizeMainStat(g.rng)
+	artifact.randomizeSubstats(g.rng, base4Chance)
+	return &artifact
+}
+
+func (g *Generator) RandomUnleveledArtifactFromDomainWithRarity(setA, setB string, rarity int) *Artifact {
+	var artifact Artifact
+	artifact.Rarity = generatedRarity(rarity)
+	artifact.randomizeSet(g.rng, setOfRarity(setA, artifact.Rarity), setOfRarity(setB, artifact.Rarity))
+	artifact.randomizeSlot(g.rng)
+	artifact.ranzomizeMainStat(g.rng)
+	artifact.randomizeSubstats(g.rng, DomainBase4Chance)
+	return &artifact
+}
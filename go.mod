@@ -1,3 +1,3 @@
 module github.com/j4rv/genshinartis
 
-go 1.17
+go 1.20
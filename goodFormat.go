@@ -1,7 +1,7 @@
 package genshinartis
 
 const goodFormatKey = "GOOD"
-const goodVersion = 1
+const goodVersion = 2
 const goodExportSource = "Jarv ArtifactGEN"
 
 type GOODArtifact struct {
@@ -20,29 +20,87 @@ type GOODSubstat struct {
 	Value float32 `json:"value"`
 }
 
+type GOODCharacter struct {
+	Key           string     `json:"key"`
+	Level         int        `json:"level"`
+	Constellation int        `json:"constellation"`
+	Ascension     int        `json:"ascension"`
+	Talent        GOODTalent `json:"talent"`
+}
+
+type GOODTalent struct {
+	Auto  int `json:"auto"`
+	Skill int `json:"skill"`
+	Burst int `json:"burst"`
+}
+
+type GOODWeapon struct {
+	Key        string `json:"key"`
+	Level      int    `json:"level"`
+	Ascension  int    `json:"ascension"`
+	Refinement int    `json:"refinement"`
+	Location   string `json:"location"`
+	Lock       bool   `json:"lock"`
+}
+
 type GOODExport struct {
-	Artifacts []GOODArtifact `json:"artifacts"`
-	Format    string         `json:"format"`
-	Version   int            `json:"version"`
-	Source    string         `json:"source"`
+	Artifacts  []GOODArtifact  `json:"artifacts"`
+	Characters []GOODCharacter `json:"characters,omitempty"`
+	Weapons    []GOODWeapon    `json:"weapons,omitempty"`
+	Materials  map[string]int  `json:"materials,omitempty"`
+	Format     string          `json:"format"`
+	Version    int             `json:"version"`
+	Source     string          `json:"source"`
+}
+
+// Account is everything a GOOD document can hold.
+// Characters, weapons and materials keep their GOOD representation.
+type Account struct {
+	Artifacts  []*Artifact
+	Characters []GOODCharacter
+	Weapons    []GOODWeapon
+	Materials  map[string]int
+}
+
+// Equip sets the given artifacts as equipped by the character,
+// unequipping the artifacts of the same slots it had equipped before
+func (acc *Account) Equip(characterKey string, arts ...*Artifact) {
+	for _, art := range arts {
+		for _, equipped := range acc.Artifacts {
+			if equipped.Location == characterKey && equipped.Slot == art.Slot {
+				equipped.Location = ""
+			}
+		}
+		art.Location = characterKey
+	}
 }
 
 func ExportToGOOD(arts []*Artifact) GOODExport {
+	return ExportAccountToGOOD(Account{Artifacts: arts})
+}
+
+func ExportAccountToGOOD(acc Account) GOODExport {
 	goodArts := []GOODArtifact{}
-	for _, a := range arts {
+	for _, a := range acc.Artifacts {
 		goodArts = append(goodArts, artifactToGOOD(a))
 	}
 	return GOODExport{
-		Artifacts: goodArts,
-		Format:    goodFormatKey,
-		Version:   goodVersion,
-		Source:    goodExportSource,
+		Artifacts:  goodArts,
+		Characters: acc.Characters,
+		Weapons:    acc.Weapons,
+		Materials:  acc.Materials,
+		Format:     goodFormatKey,
+		Version:    goodVersion,
+		Source:     goodExportSource,
 	}
 }
 
 func artifactToGOOD(art *Artifact) GOODArtifact {
 	subs := []GOODSubstat{}
 	for _, ss := range art.SubStats {
+		if ss == nil {
+			continue
+		}
 		subs = append(subs, GOODSubstat{
 			Stat:  goodStatKey(ss.Stat),
 			Value: ss.Value,
@@ -50,12 +108,12 @@ func artifactToGOOD(art *Artifact) GOODArtifact {
 	}
 	return GOODArtifact{
 		Set:      string(art.Set),
-		Rarity:   5, // TODO: Change when you implement a 4* generator!
-		Level:    20,
+		Rarity:   art.Rarity,
+		Level:    art.Level,
 		Slot:     goodSlotKey(art.Slot),
 		MainStat: goodStatKey(art.MainStat),
 		Subs:     subs,
-		Location: "",
+		Location: art.Location,
 		Lock:     false,
 	}
 }
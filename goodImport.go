@@ -0,0 +1,257 @@
+package genshinartis
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// UnknownKeyError is returned when importing a GOOD key that doesn't map to any set, slot or stat
+type UnknownKeyError struct {
+	Field string
+	Key   string
+}
+
+func (e *UnknownKeyError) Error() string {
+	return fmt.Sprintf("unknown %s: %q", e.Field, e.Key)
+}
+
+// InvalidValueError is returned when importing a value that no real artifact could have
+type InvalidValueError struct {
+	Field  string
+	Value  interface{}
+	Reason string
+}
+
+func (e *InvalidValueError) Error() string {
+	return fmt.Sprintf("invalid %s %v: %s", e.Field, e.Value, e.Reason)
+}
+
+// ArtifactImportError is returned for an artifact that couldn't be imported,
+// it wraps an UnknownKeyError or an InvalidValueError
+type ArtifactImportError struct {
+	Index int // in the GOOD document's artifacts
+	Err   error
+}
+
+func (e *ArtifactImportError) Error() string {
+	return fmt.Sprintf("artifact %d: %v", e.Index, e.Err)
+}
+
+func (e *ArtifactImportError) Unwrap() error {
+	return e.Err
+}
+
+// ImportErrors lists every artifact skipped by an import
+type ImportErrors []*ArtifactImportError
+
+func (e ImportErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("skipped %d artifacts: %s", len(e), strings.Join(msgs, "; "))
+}
+
+func (e ImportErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+// ImportFromGOOD parses a GOOD document and returns its artifacts, inferring the rolls of every substat.
+// Invalid artifacts are skipped: the valid ones are still returned, along with an ImportErrors listing the rest.
+// Any other error means the document itself couldn't be imported, and no artifacts are returned.
+func ImportFromGOOD(r io.Reader) ([]*Artifact, error) {
+	acc, err := ImportAccountFromGOOD(r)
+	return acc.Artifacts, err
+}
+
+// ImportAccountFromGOOD works like ImportFromGOOD, but it also keeps the characters, weapons and materials
+func ImportAccountFromGOOD(r io.Reader) (Account, error) {
+	var good GOODExport
+	if err := json.NewDecoder(r).Decode(&good); err != nil {
+		return Account{}, err
+	}
+	if good.Format != goodFormatKey {
+		return Account{}, &InvalidValueError{Field: "format", Value: good.Format, Reason: "expected " + goodFormatKey}
+	}
+	if good.Version < 1 || good.Version > goodVersion {
+		return Account{}, &InvalidValueError{Field: "version", Value: good.Version, Reason: "unsupported version"}
+	}
+
+	arts := []*Artifact{}
+	var skipped ImportErrors
+	for i, goodArt := range good.Artifacts {
+		art, err := artifactFromGOOD(goodArt)
+		if err != nil {
+			skipped = append(skipped, &ArtifactImportError{Index: i, Err: err})
+			continue
+		}
+		arts = append(arts, art)
+	}
+	acc := Account{
+		Artifacts:  arts,
+		Characters: good.Characters,
+		Weapons:    good.Weapons,
+		Materials:  good.Materials,
+	}
+	if len(skipped) > 0 {
+		return acc, skipped
+	}
+	return acc, nil
+}
+
+func artifactFromGOOD(goodArt GOODArtifact) (*Artifact, error) {
+	var art Artifact
+	var ok bool
+
+	art.Set = artifactSet(goodArt.Set)
+	if !isKnownArtifactSet(art.Set) {
+		return nil, &UnknownKeyError{Field: "setKey", Key: goodArt.Set}
+	}
+	if art.Slot, ok = slotFromGOODKey(goodArt.Slot); !ok {
+		return nil, &UnknownKeyError{Field: "slotKey", Key: goodArt.Slot}
+	}
+	if art.MainStat, ok = statFromGOODKey(goodArt.MainStat); !ok {
+		return nil, &UnknownKeyError{Field: "mainStatKey", Key: goodArt.MainStat}
+	}
+	if !isValidMainStat(art.Slot, art.MainStat) {
+		return nil, &InvalidValueError{Field: "mainStatKey", Value: goodArt.MainStat, Reason: "not a main stat of " + art.Slot.String()}
+	}
+
+	art.Rarity = goodArt.Rarity
+	if _, ok := substatValues[art.Rarity]; !ok {
+		return nil, &InvalidValueError{Field: "rarity", Value: art.Rarity, Reason: "unsupported rarity"}
+	}
+	art.Level = goodArt.Level
+	if art.Level < 0 || art.Level > maxLevel(art.Rarity) {
+		return nil, &InvalidValueError{Field: "level", Value: art.Level, Reason: fmt.Sprintf("out of range for a %d* artifact", art.Rarity)}
+	}
+	art.MainStatValue = mainStatValue(art.Rarity, art.Level, art.MainStat)
+
+	// GOOD exporters may fill missing substats with empty keys
+	subs := []GOODSubstat{}
+	for _, goodSub := range goodArt.Subs {
+		if goodSub.Stat != "" {
+			subs = append(subs, goodSub)
+		}
+	}
+	if len(subs) > MaxSubstats {
+		return nil, &InvalidValueError{Field: "substats", Value: len(subs), Reason: "too many substats"}
+	}
+
+	seen := map[stat]bool{}
+	possibleRolls := make([][]int, len(subs))
+	for i, goodSub := range subs {
+		s, ok := statFromGOODKey(goodSub.Stat)
+		if !ok {
+			return nil, &UnknownKeyError{Field: "substat key", Key: goodSub.Stat}
+		}
+		if _, ok := substatValues[art.Rarity][s]; !ok || s == art.MainStat || seen[s] {
+			return nil, &InvalidValueError{Field: "substat key", Value: goodSub.Stat, Reason: "not a possible substat"}
+		}
+		seen[s] = true
+		art.SubStats[i] = &ArtifactSubstat{Stat: s, Value: goodSub.Value}
+		possibleRolls[i] = substatRollCounts(art.Rarity, s, goodSub.Value)
+		if len(possibleRolls[i]) == 0 {
+			return nil, &InvalidValueError{Field: "substat " + goodSub.Stat, Value: goodSub.Value, Reason: "no amount of rolls adds up to it"}
+		}
+	}
+
+	rolls, isFourLiner, ok := inferRolls(art.Rarity, art.Level, possibleRolls)
+	if !ok {
+		return nil, &InvalidValueError{Field: "substats", Value: goodArt.Subs, Reason: fmt.Sprintf("impossible for a +%d artifact", art.Level)}
+	}
+	for i, r := range rolls {
+		art.SubStats[i].Rolls = r
+	}
+	art.IsFourLiner = isFourLiner
+	art.Location = goodArt.Location
+	return &art, nil
+}
+
+// substatRollCounts returns every amount of rolls that could add up to the value, which GOOD exports rounded
+func substatRollCounts(rarity int, s stat, value float32) []int {
+	tolerance := float32(0.051)
+	switch s {
+	case HP, ATK, DEF, ElementalMastery:
+		tolerance = 0.51
+	}
+	counts := []int{}
+	maxRolls := 1 + maxLevel(MaxRarity)/LevelsPerRoll
+	for n := 1; n <= maxRolls; n++ {
+		rolls := substatValues[rarity][s]
+		if value >= rolls[0]*float32(n)-tolerance && value <= rolls[3]*float32(n)+tolerance {
+			counts = append(counts, n)
+		}
+	}
+	return counts
+}
+
+// inferRolls picks, for every substat, one of its possible roll counts so that they add up to the rolls
+// a 3-liner (or 4-liner) of that rarity and level has. When more than one combination fits, the first one
+// with the fewest rolls on the first substats is returned. 1* artifacts always start without substats.
+func inferRolls(rarity, level int, possibleRolls [][]int) (rolls []int, isFourLiner bool, ok bool) {
+	upgrades := level / LevelsPerRoll
+	for _, initialSubs := range []int{rarity - 2, rarity - 1} {
+		if initialSubs < 0 {
+			continue
+		}
+		expectedSubs := initialSubs + upgrades
+		if expectedSubs > MaxSubstats {
+			expectedSubs = MaxSubstats
+		}
+		if expectedSubs != len(possibleRolls) {
+			continue
+		}
+		if rolls, ok := pickRolls(possibleRolls, initialSubs+upgrades); ok {
+			return rolls, initialSubs == rarity-1 && initialSubs > 0, true
+		}
+	}
+	return nil, false, false
+}
+
+func pickRolls(possibleRolls [][]int, total int) ([]int, bool) {
+	if len(possibleRolls) == 0 {
+		return []int{}, total == 0
+	}
+	for _, n := range possibleRolls[0] {
+		if rest, ok := pickRolls(possibleRolls[1:], total-n); ok {
+			return append([]int{n}, rest...), true
+		}
+	}
+	return nil, false
+}
+
+func isKnownArtifactSet(set artifactSet) bool {
+	for _, sets := range [][]artifactSet{AllArtifactSets, FourStarArtifactSets, ThreeStarArtifactSets, circletOnlyArtifactSets} {
+		for _, s := range sets {
+			if s == set {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func slotFromGOODKey(key string) (artifactSlot, bool) {
+	for slot := SlotFlower; slot <= SlotCirclet; slot++ {
+		if goodSlotKey(slot) == key {
+			return slot, true
+		}
+	}
+	return 0, false
+}
+
+func statFromGOODKey(key string) (stat, bool) {
+	for s := HP; s <= HealingBonus; s++ {
+		if goodStatKey(s) == key {
+			return s, true
+		}
+	}
+	return 0, false
+}
@@ -2,11 +2,14 @@ package genshinartis
 
 import (
 	"fmt"
-	"math/rand"
 	"sort"
 )
 
 const MaxSubstats = 4
+const MaxRarity = 5
+const MinGeneratedRarity = 3
+const MaxLevel = 20 // of a MaxRarity artifact, lower rarities cap earlier
+const LevelsPerRoll = 4
 const DomainBase4Chance = 1.0 / 5.0
 const StrongboxBase4Chance = 1.0 / 3.0
 const BossBase4Chance = 1.0 / 3.0
@@ -19,11 +22,9 @@ type ArtifactSubstat struct {
 	Value float32
 }
 
-func (s *ArtifactSubstat) randomizeValue() {
-	s.Value = 0
-	for i := 0; i < s.Rolls; i++ {
-		s.Value = s.Value + s.Stat.RandomRollValue()
-	}
+func (s *ArtifactSubstat) roll(rng RNG, rarity int) {
+	s.Rolls++
+	s.Value = s.Value + s.Stat.randomRollValue(rng, rarity)
 }
 
 func (s *ArtifactSubstat) String() string {
@@ -35,22 +36,69 @@ type Artifact struct {
 	Slot          artifactSlot
 	MainStat      stat
 	MainStatValue float32
+	Rarity        int
+	Level         int
 	SubStats      [MaxSubstats]*ArtifactSubstat
-	IsFourLiner   bool
+	IsFourLiner   bool   // for lower rarities: started with one extra substat
+	Location      string // GOOD key of the character that has it equipped, if any
+}
+
+// maxLevel returns the level at which artifacts of the given rarity are fully upgraded
+func maxLevel(rarity int) int {
+	if rarity < 3 {
+		return 4
+	}
+	return rarity * LevelsPerRoll
 }
 
 func (a Artifact) String() string {
 	subsStr := ""
 	for _, s := range a.SubStats {
-		subsStr += s.String() + "\n"
+		if s != nil {
+			subsStr += s.String() + "\n"
+		}
+	}
+	return fmt.Sprintf("Set: %s, rarity: %d, level: %d, main stat: %s\n%s", a.Set, a.Rarity, a.Level, a.MainStat, subsStr)
+}
+
+// generatedRarity returns the rarity, panicking if artifacts of it aren't generated
+func generatedRarity(rarity int) int {
+	if rarity < MinGeneratedRarity || rarity > MaxRarity {
+		panic(fmt.Sprintf("unsupported artifact rarity %d, expected %d to %d", rarity, MinGeneratedRarity, MaxRarity))
+	}
+	return rarity
+}
+
+// setOfRarity returns the set, panicking if it's a known set that doesn't drop with the rarity.
+// Unknown sets, like custom ones, are accepted with any rarity.
+func setOfRarity(set string, rarity int) artifactSet {
+	s := artifactSet(set)
+	if !isKnownArtifactSet(s) {
+		return s
+	}
+	for _, sets := range [][]artifactSet{artifactSetsOfRarity(rarity), circletOnlyArtifactSets} {
+		for _, option := range sets {
+			if option == s {
+				return s
+			}
+		}
 	}
-	return fmt.Sprintf("Set: %s, main stat: %s\n%s", a.Set, a.MainStat, subsStr)
+	panic(fmt.Sprintf("artifact set %s doesn't drop as a %d* artifact", set, rarity))
 }
 
+// subsQuality rates the substats by their weights, relative to max rolls.
+// Artifacts built by hand without a valid rarity are rated as MaxRarity ones.
 func (a Artifact) subsQuality(wantedSubWeights map[stat]float32) float32 {
+	rarity := a.Rarity
+	if _, ok := substatValues[rarity]; !ok {
+		rarity = MaxRarity
+	}
 	var quality float32
 	for _, sub := range a.SubStats {
-		maxPossibleValue := substatValues[sub.Stat][3]
+		if sub == nil {
+			continue
+		}
+		maxPossibleValue := substatValues[rarity][sub.Stat][3]
 		quality += wantedSubWeights[sub.Stat] * float32(sub.Value) / maxPossibleValue
 	}
 	return quality
@@ -59,6 +107,9 @@ func (a Artifact) subsQuality(wantedSubWeights map[stat]float32) float32 {
 func (a Artifact) cv() float32 {
 	var cv float32
 	for _, sub := range a.SubStats {
+		if sub == nil {
+			continue
+		}
 		switch sub.Stat {
 		case CritRate:
 			cv += sub.Value * 2
@@ -69,93 +120,143 @@ func (a Artifact) cv() float32 {
 	return cv
 }
 
-func (a *Artifact) randomizeSet(options ...artifactSet) {
-	a.Set = options[rand.Intn(len(options))]
+func (a *Artifact) randomizeSet(rng RNG, options ...artifactSet) {
+	a.Set = options[rng.Intn(len(options))]
 }
 
-func (a *Artifact) randomizeSlot() {
-	a.Slot = artifactSlot(rand.Intn(5))
+func (a *Artifact) randomizeSlot(rng RNG) {
+	a.Slot = artifactSlot(rng.Intn(5))
 }
 
-func (a *Artifact) ranzomizeMainStat() {
+func (a *Artifact) ranzomizeMainStat(rng RNG) {
 	switch a.Slot {
 	case SlotFlower:
 		a.MainStat = HP
 	case SlotPlume:
 		a.MainStat = ATK
 	case SlotSands:
-		a.MainStat = weightedRand(sandsWeightedStats)
+		a.MainStat = weightedRand(rng, sandsWeightedStats)
 	case SlotGoblet:
-		a.MainStat = weightedRand(gobletWeightedStats)
+		a.MainStat = weightedRand(rng, gobletWeightedStats)
 	case SlotCirclet:
-		a.MainStat = weightedRand(circletWeightedStats)
+		a.MainStat = weightedRand(rng, circletWeightedStats)
 	}
-	a.MainStatValue = mainStatValues[a.MainStat]
+	a.MainStatValue = mainStatValue(a.Rarity, a.Level, a.MainStat)
 }
 
-func (a *Artifact) randomizeSubstats(base4Chance float32) {
-	numRolls := 3 + 5 // starts with 3 subs by default
-	if rand.Float32() <= base4Chance {
-		numRolls++ // starts with 4 subs
+// randomizeSubstats rolls the substats of an unleveled artifact.
+// A 5* artifact starts with 3 of them, or 4 if it's a four liner. Every rarity below that starts with one less.
+func (a *Artifact) randomizeSubstats(rng RNG, base4Chance float32) {
+	initialSubs := MaxSubstats - 1 - (MaxRarity - a.Rarity)
+	a.IsFourLiner = false
+	if rng.Float32() <= base4Chance {
+		initialSubs++
 		a.IsFourLiner = true
 	}
 
 	a.SubStats = [MaxSubstats]*ArtifactSubstat{}
+	for i := 0; i < initialSubs; i++ {
+		a.SubStats[i] = a.newSubstat(rng)
+	}
+}
+
+// newSubstat rolls a substat that is neither the main stat nor one of the current substats
+func (a *Artifact) newSubstat(rng RNG) *ArtifactSubstat {
 	possibleStats := weightedSubstats(a.MainStat)
-	var subs [MaxSubstats]stat
-	for i := 0; i < numRolls; i++ {
-		// First 4 rolls
-		if i < MaxSubstats {
-			artiStat := weightedRand(possibleStats)
-			subs[i] = artiStat
-			a.SubStats[i] = &ArtifactSubstat{Stat: artiStat, Rolls: 1}
-			delete(possibleStats, artiStat)
-		} else {
-			// Rest of rolls
-			index := rand.Intn(MaxSubstats)
-			a.SubStats[index].Rolls += 1
+	for _, sub := range a.SubStats {
+		if sub != nil {
+			delete(possibleStats, sub.Stat)
+		}
+	}
+	substat := &ArtifactSubstat{Stat: weightedRand(rng, possibleStats)}
+	substat.roll(rng, a.Rarity)
+	return substat
+}
+
+// LevelUp increases the artifact level by one, scaling its main stat.
+// Every LevelsPerRoll levels, it adds a new substat if it has less than MaxSubstats, or upgrades a random one otherwise.
+// Does nothing if the artifact is already at the max level of its rarity.
+func (a *Artifact) LevelUp() {
+	a.levelUp(globalRNG{})
+}
+
+// LevelTo levels the artifact up until it reaches the given level (capped at the max level of its rarity)
+func (a *Artifact) LevelTo(level int) {
+	a.levelTo(globalRNG{}, level)
+}
+
+func (a *Artifact) levelUp(rng RNG) {
+	if a.Level >= maxLevel(a.Rarity) {
+		return
+	}
+	a.Level++
+	a.MainStatValue = mainStatValue(a.Rarity, a.Level, a.MainStat)
+	if a.Level%LevelsPerRoll != 0 {
+		return
+	}
+	for i, sub := range a.SubStats {
+		if sub == nil {
+			a.SubStats[i] = a.newSubstat(rng)
+			return
 		}
 	}
+	a.SubStats[rng.Intn(MaxSubstats)].roll(rng, a.Rarity)
+}
 
-	for _, substat := range a.SubStats {
-		substat.randomizeValue()
+func (a *Artifact) levelTo(rng RNG, level int) {
+	for a.Level < level && a.Level < maxLevel(a.Rarity) {
+		a.levelUp(rng)
 	}
 }
 
+// The top-level generator functions use the global math/rand source, use a Generator for reproducible results
+
 func RandomArtifact(base4Chance float32) *Artifact {
-	var artifact Artifact
-	artifact.randomizeSet(AllArtifactSets...)
-	artifact.randomizeSlot()
-	artifact.ranzomizeMainStat()
-	artifact.randomizeSubstats(base4Chance)
-	return &artifact
+	return defaultGenerator.RandomArtifact(base4Chance)
 }
 
 func RandomArtifactOfSlot(slot artifactSlot, base4Chance float32) *Artifact {
-	var artifact Artifact
-	artifact.randomizeSet(AllArtifactSets...)
-	artifact.Slot = slot
-	artifact.ranzomizeMainStat()
-	artifact.randomizeSubstats(base4Chance)
-	return &artifact
+	return defaultGenerator.RandomArtifactOfSlot(slot, base4Chance)
 }
 
 func RandomArtifactOfSet(set string, base4Chance float32) *Artifact {
-	var artifact Artifact
-	artifact.Set = artifactSet(set)
-	artifact.randomizeSlot()
-	artifact.ranzomizeMainStat()
-	artifact.randomizeSubstats(base4Chance)
-	return &artifact
+	return defaultGenerator.RandomArtifactOfSet(set, base4Chance)
 }
 
 func RandomArtifactFromDomain(setA, setB string) *Artifact {
-	var artifact Artifact
-	artifact.randomizeSet(artifactSet(setA), artifactSet(setB))
-	artifact.randomizeSlot()
-	artifact.ranzomizeMainStat()
-	artifact.randomizeSubstats(DomainBase4Chance)
-	return &artifact
+	return defaultGenerator.RandomArtifactFromDomain(setA, setB)
+}
+
+func RandomUnleveledArtifactOfSet(set string, base4Chance float32) *Artifact {
+	return defaultGenerator.RandomUnleveledArtifactOfSet(set, base4Chance)
+}
+
+func RandomUnleveledArtifactFromDomain(setA, setB string) *Artifact {
+	return defaultGenerator.RandomUnleveledArtifactFromDomain(setA, setB)
+}
+
+func RandomArtifactWithRarity(rarity int, base4Chance float32) *Artifact {
+	return defaultGenerator.RandomArtifactWithRarity(rarity, base4Chance)
+}
+
+func RandomArtifactOfSlotWithRarity(slot artifactSlot, rarity int, base4Chance float32) *Artifact {
+	return defaultGenerator.RandomArtifactOfSlotWithRarity(slot, rarity, base4Chance)
+}
+
+func RandomArtifactOfSetWithRarity(set string, rarity int, base4Chance float32) *Artifact {
+	return defaultGenerator.RandomArtifactOfSetWithRarity(set, rarity, base4Chance)
+}
+
+func RandomArtifactFromDomainWithRarity(setA, setB string, rarity int) *Artifact {
+	return defaultGenerator.RandomArtifactFromDomainWithRarity(setA, setB, rarity)
+}
+
+func RandomUnleveledArtifactOfSetWithRarity(set string, rarity int, base4Chance float32) *Artifact {
+	return defaultGenerator.RandomUnleveledArtifactOfSetWithRarity(set, rarity, base4Chance)
+}
+
+func RandomUnleveledArtifactFromDomainWithRarity(setA, setB string, rarity int) *Artifact {
+	return defaultGenerator.RandomUnleveledArtifactFromDomainWithRarity(setA, setB, rarity)
 }
 
 // RemoveTrashArtifacts processes a slice of artifacts and keeps the best ones that have the correct mainstat
@@ -0,0 +1,122 @@
+package genshinartis
+
+// Main stat values by rarity and stat, indexed by artifact level
+// From https://genshin-impact.fandom.com/wiki/Artifacts/Stats
+var mainStatValues = map[int]map[stat][]float32{
+	1: {
+		HP:               {129, 178, 227, 275, 324},
+		ATK:              {8, 11, 15, 18, 21},
+		HPP:              oneStarPercentValues,
+		ATKP:             oneStarPercentValues,
+		DEFP:             {3.9, 5.4, 6.9, 8.4, 9.9},
+		ElementalMastery: {12.6, 17.2, 21.9, 26.6, 31.3},
+		EnergyRecharge:   {3.5, 4.8, 6.1, 7.5, 8.8},
+		PyroDMG:          oneStarPercentValues,
+		ElectroDMG:       oneStarPercentValues,
+		CryoDMG:          oneStarPercentValues,
+		HydroDMG:         oneStarPercentValues,
+		AnemoDMG:         oneStarPercentValues,
+		GeoDMG:           oneStarPercentValues,
+		DendroDMG:        oneStarPercentValues,
+		PhysDMG:          {3.9, 5.4, 6.9, 8.4, 9.9},
+		CritRate:         {2.1, 2.9, 3.7, 4.5, 5.3},
+		CritDmg:          {4.2, 5.8, 7.4, 9.0, 10.5},
+		HealingBonus:     {2.4, 3.3, 4.3, 5.2, 6.1},
+	},
+	2: {
+		HP:               {258, 331, 404, 478, 551},
+		ATK:              {17, 22, 26, 31, 36},
+		HPP:              twoStarPercentValues,
+		ATKP:             twoStarPercentValues,
+		DEFP:             {5.2, 6.7, 8.2, 9.7, 11.2},
+		ElementalMastery: {16.8, 21.5, 26.3, 31.0, 35.8},
+		EnergyRecharge:   {4.7, 6.0, 7.3, 8.6, 9.9},
+		PyroDMG:          twoStarPercentValues,
+		ElectroDMG:       twoStarPercentValues,
+		CryoDMG:          twoStarPercentValues,
+		HydroDMG:         twoStarPercentValues,
+		AnemoDMG:         twoStarPercentValues,
+		GeoDMG:           twoStarPercentValues,
+		DendroDMG:        twoStarPercentValues,
+		PhysDMG:          {5.2, 6.7, 8.2, 9.7, 11.2},
+		CritRate:         {2.8, 3.6, 4.4, 5.2, 6.0},
+		CritDmg:          {5.6, 7.2, 8.8, 10.4, 11.9},
+		HealingBonus:     {3.2, 4.1, 5.1, 6.0, 6.9},
+	},
+	3: {
+		HP:               {430, 552, 674, 796, 918, 1040, 1162, 1283, 1405, 1527, 1649, 1771, 1893},
+		ATK:              {28, 36, 44, 52, 60, 68, 76, 84, 91, 99, 107, 115, 123},
+		HPP:              threeStarPercentValues,
+		ATKP:             threeStarPercentValues,
+		DEFP:             {6.6, 8.4, 10.3, 12.1, 14.0, 15.8, 17.7, 19.6, 21.4, 23.3, 25.1, 27.0, 28.8},
+		ElementalMastery: {21.0, 26.9, 32.9, 38.8, 44.8, 50.7, 56.7, 62.6, 68.5, 74.5, 80.4, 86.4, 92.3},
+		EnergyRecharge:   {5.8, 7.5, 9.1, 10.8, 12.4, 14.1, 15.7, 17.4, 19.0, 20.7, 22.3, 24.0, 25.6},
+		PyroDMG:          threeStarPercentValues,
+		ElectroDMG:       threeStarPercentValues,
+		CryoDMG:          threeStarPercentValues,
+		HydroDMG:         threeStarPercentValues,
+		AnemoDMG:         threeStarPercentValues,
+		GeoDMG:           threeStarPercentValues,
+		DendroDMG:        threeStarPercentValues,
+		PhysDMG:          {6.6, 8.4, 10.3, 12.1, 14.0, 15.8, 17.7, 19.6, 21.4, 23.3, 25.1, 27.0, 28.8},
+		CritRate:         {3.5, 4.5, 5.5, 6.5, 7.5, 8.4, 9.4, 10.4, 11.4, 12.4, 13.4, 14.4, 15.4},
+		CritDmg:          {7.0, 9.0, 11.0, 12.9, 14.9, 16.9, 18.9, 20.9, 22.8, 24.8, 26.8, 28.8, 30.8},
+		HealingBonus:     {4.0, 5.2, 6.3, 7.5, 8.6, 9.8, 10.9, 12.1, 13.2, 14.4, 15.5, 16.7, 17.8},
+	},
+	4: {
+		HP:               {645, 828, 1011, 1194, 1377, 1559, 1742, 1925, 2108, 2291, 2474, 2657, 2839, 3022, 3205, 3388, 3571},
+		ATK:              {42, 54, 66, 78, 90, 102, 113, 125, 137, 149, 161, 173, 185, 197, 209, 221, 232},
+		HPP:              fourStarPercentValues,
+		ATKP:             fourStarPercentValues,
+		DEFP:             {7.9, 10.1, 12.3, 14.6, 16.8, 19.0, 21.2, 23.5, 25.7, 27.9, 30.2, 32.4, 34.6, 36.8, 39.1, 41.3, 43.5},
+		ElementalMastery: {25.2, 32.3, 39.4, 46.6, 53.7, 60.8, 68.0, 75.1, 82.2, 89.4, 96.5, 103.6, 110.8, 117.9, 125.0, 132.2, 139.3},
+		EnergyRecharge:   {7.0, 9.0, 11.0, 12.9, 14.9, 16.9, 18.9, 20.9, 22.8, 24.8, 26.8, 28.8, 30.8, 32.8, 34.7, 36.7, 38.7},
+		PyroDMG:          fourStarPercentValues,
+		ElectroDMG:       fourStarPercentValues,
+		CryoDMG:          fourStarPercentValues,
+		HydroDMG:         fourStarPercentValues,
+		AnemoDMG:         fourStarPercentValues,
+		GeoDMG:           fourStarPercentValues,
+		DendroDMG:        fourStarPercentValues,
+		PhysDMG:          {7.9, 10.1, 12.3, 14.6, 16.8, 19.0, 21.2, 23.5, 25.7, 27.9, 30.2, 32.4, 34.6, 36.8, 39.1, 41.3, 43.5},
+		CritRate:         {4.2, 5.4, 6.6, 7.8, 9.0, 10.1, 11.3, 12.5, 13.7, 14.9, 16.1, 17.3, 18.5, 19.7, 20.8, 22.0, 23.2},
+		CritDmg:          {8.4, 10.8, 13.1, 15.5, 17.9, 20.3, 22.7, 25.0, 27.4, 29.8, 32.2, 34.5, 36.9, 39.3, 41.7, 44.1, 46.4},
+		HealingBonus:     {4.8, 6.2, 7.6, 9.0, 10.3, 11.7, 13.1, 14.4, 15.8, 17.2, 18.6, 19.9, 21.3, 22.7, 24.1, 25.4, 26.8},
+	},
+	5: {
+		HP:               {717, 920, 1123, 1326, 1530, 1733, 1936, 2139, 2342, 2545, 2749, 2952, 3155, 3358, 3561, 3764, 3967, 4171, 4374, 4577, 4780},
+		ATK:              {47, 60, 73, 86, 100, 113, 126, 139, 152, 166, 179, 192, 205, 219, 232, 245, 258, 272, 285, 298, 311},
+		HPP:              fiveStarPercentValues,
+		ATKP:             fiveStarPercentValues,
+		DEFP:             {8.7, 11.2, 13.7, 16.2, 18.6, 21.1, 23.6, 26.1, 28.6, 31.0, 33.5, 36.0, 38.5, 41.0, 43.5, 45.9, 48.4, 50.9, 53.4, 55.9, 58.3},
+		ElementalMastery: {28.0, 35.9, 43.8, 51.8, 59.7, 67.6, 75.5, 83.5, 91.4, 99.3, 107.2, 115.2, 123.1, 131.0, 138.9, 146.9, 154.8, 162.7, 170.6, 178.6, 186.5},
+		EnergyRecharge:   {7.8, 10.0, 12.2, 14.4, 16.6, 18.8, 21.0, 23.2, 25.4, 27.6, 29.8, 32.0, 34.2, 36.4, 38.6, 40.8, 43.0, 45.2, 47.4, 49.6, 51.8},
+		PyroDMG:          fiveStarPercentValues,
+		ElectroDMG:       fiveStarPercentValues,
+		CryoDMG:          fiveStarPercentValues,
+		HydroDMG:         fiveStarPercentValues,
+		AnemoDMG:         fiveStarPercentValues,
+		GeoDMG:           fiveStarPercentValues,
+		DendroDMG:        fiveStarPercentValues,
+		PhysDMG:          {8.7, 11.2, 13.7, 16.2, 18.6, 21.1, 23.6, 26.1, 28.6, 31.0, 33.5, 36.0, 38.5, 41.0, 43.5, 45.9, 48.4, 50.9, 53.4, 55.9, 58.3},
+		CritRate:         {4.7, 6.0, 7.4, 8.8, 10.1, 11.5, 12.9, 14.2, 15.6, 17.0, 18.3, 19.7, 21.1, 22.4, 23.8, 25.2, 26.5, 27.9, 29.3, 30.6, 31.1},
+		CritDmg:          {9.3, 12.0, 14.7, 17.4, 20.2, 22.9, 25.6, 28.3, 31.1, 33.8, 36.5, 39.3, 42.0, 44.7, 47.4, 50.2, 52.9, 55.6, 58.3, 61.0, 62.2},
+		HealingBonus:     {5.4, 6.9, 8.4, 10.0, 11.5, 13.0, 14.5, 16.1, 17.6, 19.1, 20.6, 22.1, 23.7, 25.2, 26.7, 28.2, 29.8, 31.3, 32.8, 34.3, 35.9},
+	},
+}
+
+// HP%, ATK% and elemental DMG% share the same values
+var oneStarPercentValues = []float32{3.1, 4.3, 5.5, 6.7, 7.9}
+var twoStarPercentValues = []float32{4.2, 5.4, 6.6, 7.8, 9.0}
+var threeStarPercentValues = []float32{5.2, 6.7, 8.2, 9.7, 11.2, 12.7, 14.2, 15.6, 17.1, 18.6, 20.1, 21.6, 23.1}
+var fourStarPercentValues = []float32{6.3, 8.1, 9.9, 11.6, 13.4, 15.2, 17.0, 18.8, 20.6, 22.3, 24.1, 25.9, 27.7, 29.5, 31.3, 33.0, 34.8}
+var fiveStarPercentValues = []float32{7.0, 9.0, 11.0, 12.9, 14.9, 16.9, 18.9, 20.9, 22.8, 24.8, 26.8, 28.8, 30.8, 32.8, 34.7, 36.7, 38.7, 40.7, 42.7, 44.6, 46.6}
+
+// mainStatValue returns 0 if the stat can't be a main stat or the level is out of range for the rarity
+func mainStatValue(rarity, level int, s stat) float32 {
+	values := mainStatValues[rarity][s]
+	if level < 0 || level >= len(values) {
+		return 0
+	}
+	return values[level]
+}
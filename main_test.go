@@ -1,12 +1,17 @@
 package genshinartis
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"log"
-	"math/rand"
+	"math"
 	"os"
+	"reflect"
 	"sort"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 )
@@ -17,19 +22,56 @@ func TestXiaoSets(t *testing.T) {
 	set := "VermillionHereafter" // MarechausseeHunter / VermillionHereafter
 	minER := float32(140)
 
-	c := character{
-		level:   90,
-		baseAtk: 349,
-		weapon:  weaponHomaPassiveOff,
-		bonusStats: map[stat]float32{
-			ATK:             1050.8,           // Benny. 1203 for Aquila, 1050.8 for Sapwood.
-			ATKP:            15,               // Tenacity, Noblesse, Pyro resonance, TTDS, etc
-			BaseDMGIncrease: 208.27,           // Faru A4
-			AnemoDMG:        32.4 + 95.2 + 15, // Faruzan, Xiao burst and Xiao A1
-			CritRate:        19.2,             // Xiao main stat
-			//CritDmg:         40,                // Faruzan c6
-		},
+	c, err := CharacterByKey("Xiao", 90)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.weapon, err = WeaponByKey("StaffOfHoma", 90, 1, condition{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for stat, value := range map[stat]float32{
+		ATKP:           15, // Tenacity, Noblesse, TTDS, etc
+		GlobalDMGBonus: 15, // Xiao A1
+		//CritDmg:         40,                // Faruzan c6
+	} {
+		c.bonusStats[stat] += value
+	}
+	bennett, err := CharacterByKey("Bennett", 90)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bennett.weapon, _ = WeaponByKey("SapwoodBlade", 90, 1, condition{}) // or AquilaFavonia
+	faruzan, err := CharacterByKey("Faruzan", 90)
+	if err != nil {
+		t.Fatal(err)
+	}
+	faruzan.weapon, _ = WeaponByKey("FavoniusWarbow", 90, 1, condition{})
+	xiaoBurst, err := TalentBuff("Xiao", "BaneOfAllEvil", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bennettBuff, err := NewBennettBurst(bennett, 13, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	faruzanBuff, err := NewFaruzanBurst(faruzan, 10)
+	if err != nil {
+		t.Fatal(err)
 	}
+	c.buffs = []buffProvider{xiaoBurst, bennettBuff, faruzanBuff, elementalResonance(Pyro)}
+
+	// the burst infuses the plunges with Anemo, the skill charges are used at the start and the end of it
+	plunge, err := TalentAttack("Xiao", "HighPlunge", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plunge.element = Anemo
+	skill, err := TalentAttack("Xiao", "Skill", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	xiaoRotation := rotation{{attack: plunge, count: 11}, {attack: skill, count: 2}}
 
 	var bestTargetValueSum float32
 	for i := 0; i < repetitions; i++ {
@@ -44,6 +86,8 @@ func TestXiaoSets(t *testing.T) {
 			artis = append(artis, RandomArtifactOfSet(set, DomainBase4Chance))
 			//artis = append(artis, RandomArtifactOfSet("VermillionHereafter", StrongboxBase4Chance))
 		}
+		// The bounded search alone takes about 17s for these 5175 pieces on one CPU,
+		// too slow for 50 repetitions, so the trash pieces are still removed first
 		subs := map[stat]float32{
 			ATK:            0.2,
 			ATKP:           0.8,
@@ -55,62 +99,26 @@ func TestXiaoSets(t *testing.T) {
 
 		config := optimizationConfig{
 			character: c,
-			target: attack{
-				element:       Anemo,
-				offensiveStat: ATK,
-				multiplier:    404,
+			rotation:  xiaoRotation,
+			enemy: enemy{
+				level:       standardEnemy.level,
+				resistances: standardEnemy.resistances,
+				resShred:    map[element]float32{Anemo: 30}, // Faruzan
 			},
 			artifacts: artis,
+			constraints: buildConstraints{
+				sets:      []setRequirement{fourPieceOf(artifactSet(set))},
+				minStats:  map[stat]float32{EnergyRecharge: minER},
+				mainStats: mainStatRules{SlotSands: {ATKP}, SlotGoblet: {AnemoDMG}, SlotCirclet: {CritRate, CritDmg}},
+			},
 		}
 
-		artifactFilter := func(unfiltered []*Artifact) []*Artifact {
-			filtered := []*Artifact{}
-			for _, art := range unfiltered {
-				if art.Slot == SlotSands {
-					if art.MainStat != ATKP {
-						continue
-					}
-				}
-				if art.Slot == SlotGoblet {
-					if !(art.MainStat == AnemoDMG) {
-						continue
-					}
-				}
-				if art.Slot == SlotCirclet {
-					if !(art.MainStat == CritRate || art.MainStat == CritDmg) {
-						continue
-					}
-				}
-				filtered = append(filtered, art)
-			}
-			return filtered
-		}
-
-		buildFilter := func(build map[artifactSlot]*Artifact) bool {
-			setCount := 0
-			var er float32
-			for _, art := range build {
-				if art.Set == artifactSet(set) {
-					setCount++
-				}
-				for _, sub := range art.SubStats {
-					if sub.Stat == EnergyRecharge {
-						er += sub.Value
-					}
-				}
-			}
-			if setCount < 4 {
-				return false
-			}
-			if 100+er < minER {
-				return false
-			}
-			return true
+		_, best, err := config.findBest(nil, nil)
+		if err != nil {
+			t.Fatal(err)
 		}
-
-		_, bestTargetValue := config.findBest(artifactFilter, buildFilter)
-		log.Printf("Best value: %v", bestTargetValue)
-		bestTargetValueSum += bestTargetValue
+		log.Printf("Best value: %v", best.average)
+		bestTargetValueSum += best.average
 	}
 
 	log.Printf("Best value AVG: %v", bestTargetValueSum/float32(repetitions))
@@ -118,13 +126,13 @@ func TestXiaoSets(t *testing.T) {
 }
 
 func TestRandomArtifactFromDomain(t *testing.T) {
-	rand.Seed(time.Now().UTC().UnixNano())
+	gen := NewSeededGenerator(1)
 	var set1Count, set2Count int
 	set1, set2 := "Emblem", "Shimenawa"
 
 	// Generate 1000 artifacts from two sets
 	for i := 0; i < 1000; i++ {
-		art := RandomArtifactFromDomain(set1, set2)
+		art := gen.RandomArtifactFromDomain(set1, set2)
 		if art.Set == artifactSet(set1) {
 			set1Count++
 		} else if art.Set == artifactSet(set2) {
@@ -144,7 +152,7 @@ func TestRandomArtifactFromDomain(t *testing.T) {
 }
 
 func TestTimeToFarmTargetRV(t *testing.T) {
-	rand.Seed(time.Now().UTC().UnixNano())
+	gen := NewSeededGenerator(1)
 	var artis []*Artifact
 	set1, set2 := "Emblem", "Shimenawa"
 	targetRV := float32(26 * 0.85)
@@ -159,58 +167,23 @@ func TestTimeToFarmTargetRV(t *testing.T) {
 		ATK:      0.25,
 	}
 
-	validArtifact := func(art *Artifact) bool {
-		if art.Slot == SlotSands {
-			if art.MainStat != ATKP {
-				return false
-			}
-		}
-		if art.Slot == SlotGoblet {
-			if !(art.MainStat == AnemoDMG) {
-				return false
-			}
-		}
-		if art.Slot == SlotCirclet {
-			if !(art.MainStat == CritRate || art.MainStat == CritDmg) {
-				return false
-			}
-		}
-		return true
-	}
-
-	buildFilter := func(build map[artifactSlot]*Artifact) bool {
-		setCount := 0
-		var er float32
-		for _, art := range build {
-			if art.Set == artifactSet(set1) {
-				setCount++
-			}
-			for _, sub := range art.SubStats {
-				if sub.Stat == EnergyRecharge {
-					er += sub.Value
-				}
-			}
-		}
-		if setCount < 4 {
-			return false
-		}
-		if 100+er < minER {
-			return false
-		}
-		return true
+	constraints := buildConstraints{
+		sets:      []setRequirement{fourPieceOf(artifactSet(set1))},
+		minStats:  map[stat]float32{EnergyRecharge: minER},
+		mainStats: mainStatRules{SlotSands: {ATKP}, SlotGoblet: {AnemoDMG}, SlotCirclet: {CritRate, CritDmg}},
 	}
 
 	domainRuns := 0
 	for i := 0; i < iterations; {
 		// one domain run
 		domainRuns++
-		art := RandomArtifactFromDomain(set1, set2)
-		if validArtifact(art) {
+		art := gen.RandomArtifactFromDomain(set1, set2)
+		if constraints.allowsPiece(art) {
 			artis = append(artis, art)
 		}
-		if rand.Float32() <= DomainExtraArtifactChance {
-			art = RandomArtifactFromDomain(set1, set2)
-			if validArtifact(art) {
+		if gen.rng.Float32() <= DomainExtraArtifactChance {
+			art = gen.RandomArtifactFromDomain(set1, set2)
+			if constraints.allowsPiece(art) {
 				artis = append(artis, art)
 			}
 		}
@@ -219,7 +192,10 @@ func TestTimeToFarmTargetRV(t *testing.T) {
 		artis = RemoveTrashArtifacts(artis, rvMultiplier, 1)
 
 		// check target RV
-		_, rv := findHighestRV(artis, rvMultiplier, nil, buildFilter)
+		_, rv, err := findHighestRV(artis, rvMultiplier, constraints, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
 		//log.Printf("Iteration %d, %d domain runs done, current max rv: %f, target: %f\n", i, domainRuns, rv, targetRV)
 		if rv >= targetRV {
 			log.Printf("Iteration %d, %d domain runs needed\n", i, domainRuns)
@@ -240,13 +216,13 @@ func TestTimeToFarmTargetRV(t *testing.T) {
 }
 
 func TestRemoveTrashArtifacts(t *testing.T) {
-	rand.Seed(time.Now().UTC().UnixNano())
+	gen := NewSeededGenerator(1)
 	var artis []*Artifact
 	set1, set2 := "Emblem", "Shimenawa"
 
 	// Generate 1000 artifacts from two sets
 	for i := 0; i < 10000; i++ {
-		artis = append(artis, RandomArtifactFromDomain(set1, set2))
+		artis = append(artis, gen.RandomArtifactFromDomain(set1, set2))
 	}
 
 	subs := map[stat]float32{
@@ -484,3 +460,1709 @@ func TestStrongbox(t *testing.T) {
 	}
 	os.WriteFile("goodStrongbox_withDendro.json", b, 0755)
 }
+
+func TestLevelUp(t *testing.T) {
+	for i := 0; i < 1000; i++ {
+		art := RandomUnleveledArtifactFromDomain("EmblemOfSeveredFate", "ShimenawasReminiscence")
+		if art.Level != 0 || art.MainStatValue != mainStatValue(MaxRarity, 0, art.MainStat) {
+			t.Fatalf("Unexpected +0 artifact: %v", art)
+		}
+		if art.IsFourLiner != (art.SubStats[MaxSubstats-1] != nil) {
+			t.Fatalf("A +0 artifact should only have 4 substats if it's a four liner: %v", art)
+		}
+
+		art.LevelTo(4)
+		for _, sub := range art.SubStats {
+			if sub == nil {
+				t.Fatalf("A +4 artifact should have %d substats: %v", MaxSubstats, art)
+			}
+		}
+
+		art.LevelTo(MaxLevel + 4)
+		if art.Level != MaxLevel || art.MainStatValue != mainStatValue(MaxRarity, MaxLevel, art.MainStat) {
+			t.Fatalf("Unexpected +%d artifact: %v", MaxLevel, art)
+		}
+		rolls := 0
+		for _, sub := range art.SubStats {
+			rolls += sub.Rolls
+		}
+		expectedRolls := MaxSubstats - 1 + MaxLevel/LevelsPerRoll
+		if art.IsFourLiner {
+			expectedRolls++
+		}
+		if rolls != expectedRolls {
+			t.Fatalf("Expected %d rolls, got %d: %v", expectedRolls, rolls, art)
+		}
+	}
+}
+
+func TestMainStatValues(t *testing.T) {
+	maxLevels := map[int]int{1: 4, 2: 4, 3: 12, 4: 16, 5: 20}
+	for rarity, values := range mainStatValues {
+		for s, v := range values {
+			if len(v) != maxLevels[rarity]+1 {
+				t.Errorf("%d* %s: expected %d values, got %d", rarity, s, maxLevels[rarity]+1, len(v))
+			}
+			for level := 1; level < len(v); level++ {
+				if v[level] <= v[level-1] {
+					t.Errorf("%d* %s: value at +%d is not higher than the previous one", rarity, s, level)
+				}
+			}
+		}
+	}
+}
+
+func TestRandomArtifactWithRarity(t *testing.T) {
+	for rarity := MinGeneratedRarity; rarity <= MaxRarity; rarity++ {
+		for i := 0; i < 1000; i++ {
+			art := RandomArtifactOfSetWithRarity(string(artifactSetsOfRarity(rarity)[0]), rarity, StrongboxBase4Chance)
+			if art.Rarity != rarity || art.Level != maxLevel(rarity) {
+				t.Fatalf("Unexpected %d* artifact: %v", rarity, art)
+			}
+			if art.MainStatValue != mainStatValue(rarity, art.Level, art.MainStat) {
+				t.Fatalf("Unexpected main stat value: %v", art)
+			}
+			rolls := 0
+			for _, sub := range art.SubStats {
+				rolls += sub.Rolls
+				if sub.Value > substatValues[rarity][sub.Stat][3]*float32(sub.Rolls) {
+					t.Fatalf("Substat value too high for a %d* artifact: %v", rarity, art)
+				}
+			}
+			expectedRolls := rarity - 2 + maxLevel(rarity)/LevelsPerRoll
+			if art.IsFourLiner {
+				expectedRolls++
+			}
+			if rolls != expectedRolls {
+				t.Fatalf("Expected %d rolls, got %d: %v", expectedRolls, rolls, art)
+			}
+		}
+	}
+}
+
+func TestUnsupportedRarity(t *testing.T) {
+	expectPanic := func(name string, rarity int, f func()) {
+		t.Helper()
+		defer func() {
+			if recover() == nil {
+				t.Errorf("Expected %s to panic for rarity %d", name, rarity)
+			}
+		}()
+		f()
+	}
+	for _, rarity := range []int{0, 2, 6} {
+		expectPanic("RandomArtifactWithRarity", rarity, func() { RandomArtifactWithRarity(rarity, StrongboxBase4Chance) })
+		expectPanic("RandomRollValueWithRarity", rarity, func() { CritRate.RandomRollValueWithRarity(rarity) })
+	}
+	expectPanic("RandomArtifactOfSetWithRarity", 5, func() { RandomArtifactOfSetWithRarity("Instructor", 5, StrongboxBase4Chance) })
+	expectPanic("RandomArtifactFromDomainWithRarity", 3, func() { RandomArtifactFromDomainWithRarity("Adventurer", "GladiatorsFinale", 3) })
+
+	// Hand-built artifacts may have any rarity, the ones without rolls are rated as MaxRarity ones
+	weights := map[stat]float32{CritRate: 1}
+	for rarity, expected := range map[int]float32{0: 3.89 / 3.89, 1: 3.89 / 0.97, 2: 3.89 / 1.55, 6: 3.89 / 3.89} {
+		art := Artifact{Rarity: rarity, SubStats: [MaxSubstats]*ArtifactSubstat{{Stat: CritRate, Value: 3.89}}}
+		if quality := art.subsQuality(weights); !nearlyEqual(quality, expected) {
+			t.Errorf("Expected a quality of %v for a %d* artifact, got %v", expected, rarity, quality)
+		}
+		if trimmed := RemoveTrashArtifacts([]*Artifact{&art, &art}, weights, 1); len(trimmed) != 1 {
+			t.Errorf("Expected to keep one %d* artifact, got %v", rarity, trimmed)
+		}
+	}
+
+	rolls := substatValues[MaxRarity][CritRate]
+	if value := CritRate.RandomRollValue(); value < rolls[0] || value > rolls[3] {
+		t.Errorf("Expected a %d* crit rate roll, got %v", MaxRarity, value)
+	}
+}
+
+func TestSeededGenerator(t *testing.T) {
+	genA, genB := NewSeededGenerator(42), NewSeededGenerator(42)
+	for i := 0; i < 1000; i++ {
+		artA := genA.RandomUnleveledArtifactFromDomain("EmblemOfSeveredFate", "ShimenawasReminiscence")
+		artB := genB.RandomUnleveledArtifactFromDomain("EmblemOfSeveredFate", "ShimenawasReminiscence")
+		genA.LevelTo(artA, 4)
+		genB.LevelTo(artB, 4)
+		if artA.String() != artB.String() {
+			t.Fatalf("Generators with the same seed generated different artifacts:\n%v\n%v", artA, artB)
+		}
+		artA, artB = genA.RandomArtifactWithRarity(4, StrongboxBase4Chance), genB.RandomArtifactWithRarity(4, StrongboxBase4Chance)
+		if artA.String() != artB.String() {
+			t.Fatalf("Generators with the same seed generated different artifacts:\n%v\n%v", artA, artB)
+		}
+	}
+}
+
+func TestImportFromGOOD(t *testing.T) {
+	gen := NewSeededGenerator(1)
+	var artis []*Artifact
+	for i := 0; i < 500; i++ {
+		art := gen.RandomUnleveledArtifactFromDomain("EmblemOfSeveredFate", "ShimenawasReminiscence")
+		gen.LevelTo(art, i%(MaxLevel+1))
+		artis = append(artis, art)
+		artis = append(artis, gen.RandomArtifactOfSetWithRarity("Instructor", 4, StrongboxBase4Chance))
+	}
+	b, err := json.Marshal(ExportToGOOD(artis))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	imported, err := ImportFromGOOD(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(imported) != len(artis) {
+		t.Fatalf("Expected %d artifacts, got %d", len(artis), len(imported))
+	}
+	for i, art := range imported {
+		original := artis[i]
+		if art.Set != original.Set || art.Slot != original.Slot || art.MainStat != original.MainStat ||
+			art.MainStatValue != original.MainStatValue || art.Rarity != original.Rarity || art.Level != original.Level {
+			t.Fatalf("Imported artifact doesn't match the original:\n%v\n%v", art, original)
+		}
+		for j, sub := range art.SubStats {
+			if (sub == nil) != (original.SubStats[j] == nil) {
+				t.Fatalf("Imported artifact doesn't match the original:\n%v\n%v", art, original)
+			}
+			if sub != nil && (sub.Stat != original.SubStats[j].Stat || sub.Value != original.SubStats[j].Value) {
+				t.Fatalf("Imported artifact doesn't match the original:\n%v\n%v", art, original)
+			}
+		}
+	}
+
+	imported, err = ImportFromGOOD(strings.NewReader(`{"format":"GOOD","version":1,"artifacts":[
+		{"setKey":"GladiatorsFinale","rarity":5,"level":0,"slotKey":"hat","mainStatKey":"critRate_","substats":[]},
+		{"setKey":"GladiatorsFinale","rarity":5,"level":0,"slotKey":"circlet","mainStatKey":"critRate_","substats":[
+			{"key":"critDMG_","value":7.8},{"key":"atk_","value":5.8},{"key":"hp","value":299}]},
+		{"setKey":"GladiatorsFinale","rarity":5,"level":0,"slotKey":"circlet","mainStatKey":"critRate_","substats":[
+			{"key":"critDMG_","value":30},{"key":"atk_","value":5.8},{"key":"hp","value":209}]}]}`))
+	if len(imported) != 1 || imported[0].Slot != SlotCirclet {
+		t.Errorf("Expected the valid artifact to be imported, got %v", imported)
+	}
+	var skipped ImportErrors
+	if !errors.As(err, &skipped) || len(skipped) != 2 || skipped[0].Index != 0 || skipped[1].Index != 2 {
+		t.Fatalf("Expected the first and last artifacts to be skipped, got %v", err)
+	}
+	var unknownKeyErr *UnknownKeyError
+	if !errors.As(skipped[0], &unknownKeyErr) || unknownKeyErr.Key != "hat" {
+		t.Errorf("Expected an UnknownKeyError, got %v", skipped[0])
+	}
+	var invalidValueErr *InvalidValueError
+	if !errors.As(skipped[1], &invalidValueErr) {
+		t.Errorf("Expected an InvalidValueError, got %v", skipped[1])
+	}
+	if !errors.As(err, &unknownKeyErr) {
+		t.Errorf("Expected the UnknownKeyError to be reachable from the ImportErrors, got %v", err)
+	}
+
+	imported, err = ImportFromGOOD(strings.NewReader(`{"format":"GOOD","version":1,"artifacts":[
+		{"setKey":"Adventurer","rarity":1,"level":4,"slotKey":"plume","mainStatKey":"atk","substats":[{"key":"atk_","value":1.4}]},
+		{"setKey":"LuckyDog","rarity":2,"level":4,"slotKey":"flower","mainStatKey":"hp","substats":[
+			{"key":"critRate_","value":1.5},{"key":"def_","value":2.0}]}]}`))
+	if err != nil || len(imported) != 2 {
+		t.Fatalf("Expected the 1* and 2* artifacts to be imported, got %v and %v", imported, err)
+	}
+	if imported[0].SubStats[0].Rolls != 1 || imported[0].IsFourLiner || !imported[1].IsFourLiner {
+		t.Errorf("Unexpected rolls of the 1* and 2* artifacts: %v %v", imported[0], imported[1])
+	}
+
+	imported, err = ImportFromGOOD(strings.NewReader(`{"format":"GOOD","version":99,"artifacts":[]}`))
+	if !errors.As(err, &invalidValueErr) || invalidValueErr.Field != "version" || imported != nil {
+		t.Errorf("Expected the whole document to be rejected, got %v and %v", imported, err)
+	}
+}
+
+func TestExportAccountToGOOD(t *testing.T) {
+	gen := NewSeededGenerator(2)
+	acc := Account{
+		Characters: []GOODCharacter{{Key: "Xiao", Level: 90, Constellation: 0, Ascension: 6, Talent: GOODTalent{Auto: 10, Skill: 9, Burst: 10}}},
+		Weapons:    []GOODWeapon{{Key: "StaffOfHoma", Level: 90, Ascension: 6, Refinement: 1, Location: "Xiao"}},
+		Materials:  map[string]int{"MysticEnhancementOre": 200},
+	}
+	for slot := SlotFlower; slot <= SlotCirclet; slot++ {
+		acc.Artifacts = append(acc.Artifacts, gen.RandomArtifactOfSlot(slot, DomainBase4Chance))
+	}
+	acc.Equip("Xiao", acc.Artifacts...)
+	replacement := gen.RandomArtifactOfSlot(SlotFlower, DomainBase4Chance)
+	acc.Artifacts = append(acc.Artifacts, replacement)
+	acc.Equip("Xiao", replacement)
+
+	export := ExportAccountToGOOD(acc)
+	if export.Version != 2 {
+		t.Errorf("Expected GOOD version 2, got %d", export.Version)
+	}
+	b, err := json.Marshal(export)
+	if err != nil {
+		t.Fatal(err)
+	}
+	imported, err := ImportAccountFromGOOD(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(imported.Characters) != 1 || imported.Characters[0] != acc.Characters[0] {
+		t.Errorf("Unexpected characters: %v", imported.Characters)
+	}
+	if len(imported.Weapons) != 1 || imported.Weapons[0] != acc.Weapons[0] {
+		t.Errorf("Unexpected weapons: %v", imported.Weapons)
+	}
+	if imported.Materials["MysticEnhancementOre"] != 200 {
+		t.Errorf("Unexpected materials: %v", imported.Materials)
+	}
+	equipped := 0
+	for i, art := range imported.Artifacts {
+		if art.Location != acc.Artifacts[i].Location {
+			t.Errorf("Unexpected location of artifact %d: %q", i, art.Location)
+		}
+		if art.Location == "Xiao" {
+			equipped++
+		}
+	}
+	if equipped != 5 || imported.Artifacts[0].Location != "" {
+		t.Errorf("Expected the replaced flower to be unequipped and 5 artifacts equipped, got %d", equipped)
+	}
+}
+
+func TestArtifactSetBonus(t *testing.T) {
+	for _, set := range artifactSetsOfRarity(1) {
+		if _, ok := setBonuses[set]; !ok {
+			t.Errorf("Missing set bonus of %s", set)
+		}
+	}
+	for _, set := range artifactSetsOfRarity(5) {
+		if _, ok := setBonuses[set]; !ok {
+			t.Errorf("Missing set bonus of %s", set)
+		}
+	}
+	instructor := map[artifactSlot]*Artifact{}
+	for slot := SlotFlower; slot <= SlotCirclet; slot++ {
+		instructor[slot] = &Artifact{Set: "Instructor", Slot: slot}
+	}
+	if em := artifactSetBonus(instructor, nil)[ElementalMastery]; em != 200 {
+		t.Errorf("Expected 200 EM from 4pc Instructor, got %v", em)
+	}
+
+	build := map[artifactSlot]*Artifact{}
+	for slot := SlotFlower; slot <= SlotCirclet; slot++ {
+		build[slot] = &Artifact{Set: "EmblemOfSeveredFate", Slot: slot}
+	}
+	build[SlotCirclet].Set = "NoblesseOblige"
+	bonus := artifactSetBonus(build, nil)
+	if len(bonus) != 1 || bonus[EnergyRecharge] != 20 {
+		t.Errorf("Unexpected 4pc Emblem bonus: %v", bonus)
+	}
+	c := character{artifacts: build, weapon: weapon{stats: map[stat]float32{EnergyRecharge: 280}}}
+	if burstDMG := c.stats()[BurstDMG]; burstDMG != 75 {
+		t.Errorf("Expected the 4pc Emblem burst DMG bonus to be capped at 75%%, got %v", burstDMG)
+	}
+
+	build[SlotFlower].Set = "NoblesseOblige"
+	build[SlotPlume].Set = "NoblesseOblige"
+	bonus = artifactSetBonus(build, nil)
+	if len(bonus) != 2 || bonus[EnergyRecharge] != 20 || bonus[BurstDMG] != 20 {
+		t.Errorf("Unexpected 2pc Emblem 2pc Noblesse bonus: %v", bonus)
+	}
+}
+
+func TestSetConditions(t *testing.T) {
+	build := map[artifactSlot]*Artifact{}
+	for slot := SlotFlower; slot <= SlotCirclet; slot++ {
+		build[slot] = &Artifact{Set: "VermillionHereafter", Slot: slot}
+	}
+
+	tests := []struct {
+		conditions map[artifactSet]condition
+		expected   float32
+	}{
+		{nil, 18 + 8 + 10*4},
+		{map[artifactSet]condition{"VermillionHereafter": alwaysActive(2)}, 18 + 8 + 10*2},
+		{map[artifactSet]condition{"VermillionHereafter": alwaysActive(10)}, 18 + 8 + 10*4},
+		{map[artifactSet]condition{"VermillionHereafter": activeFor(4, 0.5)}, 18 + (8+10*4)*0.5},
+		{map[artifactSet]condition{"VermillionHereafter": activeFor(4, 1)}, 18 + 8 + 10*4},
+		{map[artifactSet]condition{"VermillionHereafter": activeFor(4, 0)}, 18},
+		{map[artifactSet]condition{"VermillionHereafter": activeFor(4, 2)}, 18 + 8 + 10*4},
+		{map[artifactSet]condition{"VermillionHereafter": activeFor(4, -1)}, 18},
+		{map[artifactSet]condition{"VermillionHereafter": {}}, 18},
+	}
+	for _, test := range tests {
+		if atk := artifactSetBonus(build, test.conditions)[ATKP]; atk != test.expected {
+			t.Errorf("Expected %v ATK%% with conditions %v, got %v", test.expected, test.conditions, atk)
+		}
+	}
+}
+
+func TestEnemyMultipliers(t *testing.T) {
+	closeTo := func(a, b float32) bool {
+		return a-b < 0.0001 && b-a < 0.0001
+	}
+	if m := standardEnemy.defMultiplier(90); !closeTo(m, 0.5) {
+		t.Errorf("Expected a 0.5 DEF multiplier, got %v", m)
+	}
+	reduced := enemy{level: 90, defReduction: 30, defIgnore: 0}
+	if m := reduced.defMultiplier(90); !closeTo(m, 190/(190+190*0.7)) {
+		t.Errorf("Unexpected DEF multiplier with DEF reduction: %v", m)
+	}
+
+	e := enemy{
+		resistances: map[element]float32{Anemo: 10, Geo: 10, Cryo: 80},
+		resShred:    map[element]float32{Anemo: 40, Geo: 10},
+	}
+	tests := map[element]float32{
+		Anemo:    1.15,
+		Geo:      1,
+		Cryo:     1 / 4.2,
+		Physical: 1,
+	}
+	for el, expected := range tests {
+		if m := e.resMultiplier(el); !closeTo(m, expected) {
+			t.Errorf("Expected a %v RES multiplier for element %d, got %v", expected, el, m)
+		}
+	}
+}
+
+func TestDMGBonusMultiplier(t *testing.T) {
+	c := optimizationConfig{
+		character: character{
+			level:      90,
+			baseAtk:    100,
+			bonusStats: map[stat]float32{CritRate: 95, PyroDMG: 50, AnemoDMG: 20, PlungeDMG: 30, BurstDMG: 1000, GlobalDMGBonus: 10},
+		},
+		rotation: singleHit(attack{tag: PlungeAttack, element: Anemo, offensiveStat: ATK, multiplier: 100}),
+		enemy:    standardEnemy,
+	}
+	withoutBonus := c
+	withoutBonus.character.bonusStats = map[stat]float32{CritRate: 95}
+
+	ratio := c.calculateTargetValue().average / withoutBonus.calculateTargetValue().average
+	if ratio < 1.5999 || ratio > 1.6001 {
+		t.Errorf("Expected only the Anemo, Plunge and global DMG bonuses to apply (x1.6), got x%v", ratio)
+	}
+}
+
+func TestDefaultEnemy(t *testing.T) {
+	c := optimizationConfig{
+		character: character{level: 90, baseAtk: 100, bonusStats: map[stat]float32{CritRate: 50, CritDmg: 100}},
+		rotation:  singleHit(attack{tag: NormalAttack, element: Physical, offensiveStat: ATK, multiplier: 100}),
+	}
+	withEnemy := c
+	withEnemy.enemy = standardEnemy
+	if got, expected := c.calculateTargetValue().average, withEnemy.calculateTargetValue().average; got != expected {
+		t.Errorf("Expected an unset enemy to take the damage of the standard one (%v), got %v", expected, got)
+	}
+}
+
+func TestAttackTagStats(t *testing.T) {
+	c := optimizationConfig{
+		character: character{
+			level:      90,
+			baseAtk:    100,
+			bonusStats: map[stat]float32{BurstCritRate: 95, BurstCritDmg: 50, BurstDMGIncrease: 100, PlungeCritRate: 45},
+		},
+		rotation: singleHit(attack{tag: ElementalBurst, element: Anemo, offensiveStat: ATK, multiplier: 100}),
+		enemy:    standardEnemy,
+	}
+	burst := c.calculateTargetValue().average
+	c.rotation[0].attack.tag = PlungeAttack
+	plunge := c.calculateTargetValue().average
+	c.rotation[0].attack.tag = ReactionDamage
+	untagged := c.calculateTargetValue().average
+
+	// Burst: 100% crit rate, 100% crit DMG and twice the base DMG, plunge: 50% crit rate and 50% crit DMG
+	expected := float32(2*(1+1*1)) / (1 + 0.5*0.5)
+	if ratio := burst / plunge; ratio < expected-0.001 || ratio > expected+0.001 {
+		t.Errorf("Expected the burst to deal x%v the plunge damage, got x%v", expected, ratio)
+	}
+	if untagged >= plunge {
+		t.Errorf("Tag specific stats should not apply to untagged damage")
+	}
+}
+
+func TestAmplifyingReactions(t *testing.T) {
+	tests := []struct {
+		attack   attack
+		stats    map[stat]float32
+		expected float32
+	}{
+		{attack{element: Pyro, reaction: Vaporize}, map[stat]float32{}, 1.5},
+		{attack{element: Hydro, reaction: Vaporize}, map[stat]float32{}, 2},
+		{attack{element: Pyro, reaction: Melt}, map[stat]float32{ElementalMastery: 1400}, 2 * (1 + 1.39)},
+		{attack{element: Cryo, reaction: Melt}, map[stat]float32{MeltDMG: 15}, 1.5 * 1.15},
+		{attack{element: Hydro, reaction: Melt}, map[stat]float32{ElementalMastery: 1400}, 1},
+		{attack{element: Pyro}, map[stat]float32{ElementalMastery: 1400}, 1},
+	}
+	for _, test := range tests {
+		if m := reactionMultiplier(test.attack, test.stats); m < test.expected-0.0001 || m > test.expected+0.0001 {
+			t.Errorf("Expected a %v reaction multiplier for %v, got %v", test.expected, test.attack, m)
+		}
+	}
+}
+
+func TestTransformativeAndAdditiveReactions(t *testing.T) {
+	c := optimizationConfig{
+		character: character{level: 90, baseAtk: 100, bonusStats: map[stat]float32{ElementalMastery: 1000, CritRate: 95}},
+		rotation:  singleHit(attack{tag: ReactionDamage, element: Dendro, reaction: Hyperbloom}),
+		enemy:     standardEnemy,
+	}
+	expected := float32(3 * 1446.8535 * (1 + 16.0/3) * 0.9)
+	if dmg := c.calculateTargetValue().average; dmg < expected-0.1 || dmg > expected+0.1 {
+		t.Errorf("Expected %v hyperbloom damage, got %v", expected, dmg)
+	}
+
+	c.rotation = singleHit(attack{tag: ReactionDamage, element: Hydro, reaction: Swirl})
+	c.enemy.resShred = map[element]float32{Hydro: 40}
+	expected = float32(0.6 * 1446.8535 * (1 + 16.0/3) * 1.15)
+	if dmg := c.calculateTargetValue().average; dmg < expected-0.1 || dmg > expected+0.1 {
+		t.Errorf("Expected %v hydro swirl damage, got %v", expected, dmg)
+	}
+
+	c.character.bonusStats = map[stat]float32{CritRate: 95}
+	c.rotation = singleHit(attack{tag: ElementalSkill, element: Electro, reaction: Aggravate, offensiveStat: ATK})
+	aggravate := c.calculateTargetValue().average
+	c.character.bonusStats[BaseDMGIncrease] = 1.15 * 1446.8535
+	c.rotation[0].attack.reaction = NoReaction
+	if noReaction := c.calculateTargetValue().average; aggravate < noReaction-0.1 || aggravate > noReaction+0.1 {
+		t.Errorf("Expected aggravate to add %v base DMG, got %v instead of %v", 1.15*1446.8535, aggravate, noReaction)
+	}
+}
+
+func TestCharacterByKey(t *testing.T) {
+	xiao, err := CharacterByKey("Xiao", 90)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if xiao.baseHP != 12736 || xiao.baseAtk != 349 || xiao.baseDef != 799 || xiao.bonusStats[CritRate] != 19.2 || xiao.element != Anemo {
+		t.Errorf("Unexpected level 90 Xiao: %+v", xiao)
+	}
+
+	// In-game base HP at some breakpoints. The growth is approximated, see characterGrowth
+	expectedHP := []struct {
+		level, ascension int
+		hp               float32
+	}{
+		{1, 0, 991}, {20, 0, 2572}, {80, 6, 11777},
+	}
+	for _, expected := range expectedHP {
+		xiao, err := CharacterByKeyAndAscension("Xiao", expected.level, expected.ascension)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if xiao.baseHP < expected.hp*0.98 || xiao.baseHP > expected.hp*1.02 {
+			t.Errorf("Expected ~%v base HP at level %d/A%d, got %v", expected.hp, expected.level, expected.ascension, xiao.baseHP)
+		}
+	}
+
+	var previousHP float32
+	for ascension, maxLevel := range ascensionMaxLevels {
+		for _, level := range []int{maxLevel - 10, maxLevel} {
+			if ascension > 0 && level < ascensionMaxLevels[ascension-1] {
+				continue
+			}
+			xiao, err := CharacterByKeyAndAscension("Xiao", level, ascension)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if xiao.baseHP <= previousHP {
+				t.Errorf("Base HP at level %d/A%d should be higher than the previous breakpoint", level, ascension)
+			}
+			previousHP = xiao.baseHP
+		}
+	}
+
+	if bennett, _ := CharacterFromGOOD(GOODCharacter{Key: "Bennett", Level: 80, Ascension: 6}); bennett.bonusStats[EnergyRecharge] != 26.7 {
+		t.Errorf("Expected a fully ascended Bennett to have 26.7%% ER, got %v", bennett.bonusStats[EnergyRecharge])
+	}
+	if mavuika, err := CharacterByKey("Mavuika", 90); err != nil || mavuika.element != Pyro || mavuika.weaponType != Claymore {
+		t.Errorf("Unexpected level 90 Mavuika: %+v, %v", mavuika, err)
+	}
+	if _, err := CharacterByKeyAndAscension("Xiao", 90, 5); err == nil {
+		t.Errorf("Expected an error for a level 90 character at ascension 5")
+	}
+	var unknownKeyErr *UnknownKeyError
+	if _, err := CharacterByKey("Paimon", 90); !errors.As(err, &unknownKeyErr) {
+		t.Errorf("Expected an UnknownKeyError, got %v", err)
+	}
+}
+
+func TestLoadGrowthCurves(t *testing.T) {
+	defer func(loaded map[string]growthCurve) { growthCurves = loaded }(growthCurves)
+	growthCurves = map[string]growthCurve{}
+
+	// Made up curves, only their shape matters
+	type curveInfo struct {
+		Type  string  `json:"type"`
+		Value float32 `json:"value"`
+	}
+	type curveLevel struct {
+		Level      int         `json:"level"`
+		CurveInfos []curveInfo `json:"curveInfos"`
+	}
+	var levels []curveLevel
+	for level := 1; level <= 100; level++ {
+		levels = append(levels, curveLevel{level, []curveInfo{
+			{"GROW_CURVE_HP_S5", float32(level * level)},
+			{"GROW_CURVE_ATTACK_S5", float32(level)},
+		}})
+	}
+	b, err := json.Marshal(levels)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadGrowthCurves(bytes.NewReader(b)); err != nil {
+		t.Fatal(err)
+	}
+
+	growth := characterGrowth[5]
+	xiao, err := CharacterByKeyAndAscension("Xiao", 45, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ascensionShare := growth.ascension * ascensionBaseStatShare[2]
+	if expected := 12736 * ((1-growth.ascension)*45*45/(90*90) + ascensionShare); !nearlyEqual(xiao.baseHP, expected) {
+		t.Errorf("Expected %v base HP following the HP curve, got %v", expected, xiao.baseHP)
+	}
+	if expected := 349 * ((1-growth.ascension)*45/90 + ascensionShare); !nearlyEqual(xiao.baseAtk, expected) {
+		t.Errorf("Expected %v base ATK following the ATK curve, got %v", expected, xiao.baseAtk)
+	}
+	if bennett, _ := CharacterByKey("Bennett", 1); bennett.baseHP != 12397*characterGrowth[4].level1*(1-characterGrowth[4].ascension) {
+		t.Errorf("Expected the linear growth without a curve for the rarity, got %v", bennett.baseHP)
+	}
+
+	levels = levels[:0]
+	for level := 1; level <= 90; level++ {
+		levels = append(levels, curveLevel{level, []curveInfo{
+			{weaponAtkCurves[608].curve, float32(level)},
+			{weaponGrowth[5].subCurve, float32(level * level)},
+		}})
+	}
+	if b, err = json.Marshal(levels); err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadGrowthCurves(bytes.NewReader(b)); err != nil {
+		t.Fatal(err)
+	}
+	homa, err := WeaponByKeyAndAscension("StaffOfHoma", 45, 2, 1, condition{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	weaponAscension := weaponGrowth[5].ascension
+	if expected := (608-weaponAscension)*45/90 + weaponAscension*2/6; !nearlyEqual(homa.baseAtk, expected) {
+		t.Errorf("Expected %v base ATK following the weapon ATK curve, got %v", expected, homa.baseAtk)
+	}
+	if expected := float32(66.2 * 45 * 45 / (90 * 90)); !nearlyEqual(homa.stats[CritDmg], expected) {
+		t.Errorf("Expected %v CRIT DMG following the substat curve, got %v", expected, homa.stats[CritDmg])
+	}
+	if _, ok := growthCurves["GROW_CURVE_HP_S5"]; !ok {
+		t.Errorf("Loading weapon curves shouldn't drop the character ones")
+	}
+
+	var invalidValueErr *InvalidValueError
+	err = LoadGrowthCurves(strings.NewReader(`[{"level":1,"curveInfos":[{"type":"GROW_CURVE_HP_S4","value":1}]}]`))
+	if !errors.As(err, &invalidValueErr) || invalidValueErr.Field != "GROW_CURVE_HP_S4" {
+		t.Errorf("Expected an InvalidValueError for an incomplete curve, got %v", err)
+	}
+	if _, ok := growthCurves["GROW_CURVE_HP_S4"]; ok {
+		t.Errorf("An invalid file shouldn't load any curve")
+	}
+}
+
+func TestWeaponByKey(t *testing.T) {
+	closeTo := func(a, b float32) bool { return math.Abs(float64(a-b)) <= 0.01*math.Abs(float64(b)) }
+
+	homa, err := WeaponByKey("StaffOfHoma", 90, 1, condition{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if homa.baseAtk != 608 || !closeTo(homa.stats[CritDmg], 66.2) || homa.stats[HPP] != 20 {
+		t.Errorf("unexpected Homa R1 stats: %v %v", homa.baseAtk, homa.stats)
+	}
+	if atk := homa.conversion(map[stat]float32{HP: 30000})[ATK]; !closeTo(atk, 240) {
+		t.Errorf("expected 240 ATK from the Homa passive above 50%% HP, got %v", atk)
+	}
+	homa, _ = WeaponByKey("StaffOfHoma", 90, 5, alwaysActive(0))
+	if homa.stats[HPP] != 40 {
+		t.Errorf("expected 40 HP%% on Homa R5, got %v", homa.stats[HPP])
+	}
+	if atk := homa.conversion(map[stat]float32{HP: 30000})[ATK]; !closeTo(atk, 1020) {
+		t.Errorf("expected 1020 ATK from the Homa R5 passive below 50%% HP, got %v", atk)
+	}
+
+	// level curves, compared with known values
+	lv1, _ := WeaponByKey("StaffOfHoma", 1, 1, condition{})
+	if !closeTo(lv1.baseAtk, 46) || !closeTo(lv1.stats[CritDmg], 14.4) {
+		t.Errorf("unexpected Homa level 1 stats: %v %v", lv1.baseAtk, lv1.stats)
+	}
+	catch, _ := WeaponByKey("TheCatch", 1, 5, condition{})
+	if !closeTo(catch.baseAtk, 42) || catch.stats[BurstCritRate] != 12 || catch.stats[BurstDMG] != 32 {
+		t.Errorf("unexpected The Catch level 1 R5 stats: %v %v", catch.baseAtk, catch.stats)
+	}
+	for key, atk := range map[string]float32{"AquilaFavonia": 48, "PrimordialJadeCutter": 44, "SongOfBrokenPines": 49, "WavebreakersFin": 45} {
+		if w, _ := WeaponByKey(key, 1, 1, condition{}); !closeTo(w.baseAtk, atk) {
+			t.Errorf("expected %v base ATK on a level 1 %s, got %v", atk, key, w.baseAtk)
+		}
+	}
+
+	widsith, _ := WeaponByKey("Widsith", 90, 1, alwaysActive(2))
+	if widsith.stats[PyroDMG] != 48 || widsith.stats[ATKP] != 0 || !closeTo(widsith.stats[CritDmg], 55.1) {
+		t.Errorf("unexpected Widsith stats during Aria: %v", widsith.stats)
+	}
+	wavebreaker, _ := WeaponByKey("WavebreakersFin", 90, 1, alwaysActive(320))
+	if !closeTo(wavebreaker.stats[BurstDMG], 38.4) {
+		t.Errorf("expected 38.4%% burst DMG from Wavebreaker's Fin with 320 party Energy, got %v", wavebreaker.stats[BurstDMG])
+	}
+	for _, key := range []string{"EverlastingMoonglow", "Verdict", "BlackcliffPole", "PrototypeAmber", "RoyalGrimoire", "FleuveCendreFerryman", "KagotsurubeIsshin",
+		"FavoniusGreatsword", "TheBell", "Akuoumaru", "LithicSpear", "Hamayumi", "TheViridescentHunt", "WindblumeOde", "EyeOfPerception",
+		"OathswornEye", "HakushinRing", "WanderingEvenstar", "FruitOfFulfillment", "MakhairaAquamarine", "ForestRegalia"} {
+		if _, err := WeaponByKey(key, 90, 1, condition{}); err != nil {
+			t.Errorf("expected %s to be in the catalog: %v", key, err)
+		}
+	}
+
+	pjws, _ := WeaponByKey("PrimordialJadeWingedSpear", 90, 1, activeFor(9, 0.5))
+	if !closeTo(pjws.stats[ATKP], 3.2*7/2) || pjws.stats[GlobalDMGBonus] != 6 {
+		t.Errorf("unexpected PJWS stats at half uptime: %v", pjws.stats)
+	}
+
+	engulfing, _ := WeaponByKey("EngulfingLightning", 90, 1, condition{})
+	if atk := engulfing.conversion(map[stat]float32{EnergyRecharge: 200, BaseATK: 1000})[ATK]; !closeTo(atk, 280) {
+		t.Errorf("expected 280 ATK from the Engulfing passive, got %v", atk)
+	}
+	if atk := engulfing.conversion(map[stat]float32{EnergyRecharge: 500, BaseATK: 1000})[ATK]; !closeTo(atk, 800) {
+		t.Errorf("expected the Engulfing passive capped at 800 ATK, got %v", atk)
+	}
+	makhaira, _ := WeaponByKey("MakhairaAquamarine", 90, 5, condition{})
+	if atk := makhaira.conversion(map[stat]float32{ElementalMastery: 500})[ATK]; !closeTo(atk, 240) {
+		t.Errorf("expected 240 ATK from the Makhaira passive with 500 EM, got %v", atk)
+	}
+	hamayumi, _ := WeaponByKey("Hamayumi", 90, 1, alwaysActive(0))
+	if hamayumi.stats[NormalAttackDMG] != 32 || hamayumi.stats[ChargedAttackDMG] != 24 {
+		t.Errorf("expected Hamayumi's bonus doubled with full Energy, got %v", hamayumi.stats)
+	}
+
+	for key := range weapons {
+		for r := 1; r <= MaxRefinement; r++ {
+			if _, err := WeaponByKey(key, 90, r, alwaysActive(weapons[key].maxStacks)); err != nil {
+				t.Errorf("%s R%d: %v", key, r, err)
+			}
+		}
+	}
+
+	var unknownKey *UnknownKeyError
+	if _, err := WeaponByKey("Excalibur", 90, 1, condition{}); !errors.As(err, &unknownKey) {
+		t.Errorf("expected an UnknownKeyError, got %v", err)
+	}
+	var invalidValue *InvalidValueError
+	if _, err := WeaponByKey("WolfFang", 90, 1, condition{}); !errors.As(err, &invalidValue) {
+		t.Errorf("expected an InvalidValueError for a weapon without data, got %v", err)
+	}
+	if _, err := WeaponByKey("CashflowSupervision", 90, 1, condition{}); !errors.As(err, &invalidValue) {
+		t.Errorf("expected an InvalidValueError for a 5* weapon without data, got %v", err)
+	}
+	for key := range weaponsWithoutData {
+		if _, ok := weapons[key]; ok {
+			t.Errorf("%s has data but is in weaponsWithoutData", key)
+		}
+	}
+	for key, data := range weapons {
+		if _, ok := weaponAtkCurves[data.atk]; !ok {
+			t.Errorf("%s has no ATK curve for its %v base ATK", key, data.atk)
+		}
+	}
+	if _, err := WeaponByKey("StaffOfHoma", 90, 6, condition{}); !errors.As(err, &invalidValue) {
+		t.Errorf("expected an InvalidValueError for refinement 6, got %v", err)
+	}
+	if _, err := WeaponFromGOOD(GOODWeapon{Key: "StaffOfHoma", Level: 80, Ascension: 4, Refinement: 1}, condition{}); err == nil {
+		t.Errorf("expected an error for an impossible ascension")
+	}
+}
+
+func TestTalentAttack(t *testing.T) {
+	plunge, err := TalentAttack("Xiao", "HighPlunge", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plunge.tag != PlungeAttack || plunge.element != Physical || plunge.multiplier < 403.9 || plunge.multiplier > 404.1 {
+		t.Errorf("unexpected Xiao high plunge at level 10: %+v", plunge)
+	}
+	skill, _ := TalentAttack("Xiao", "Skill", 13)
+	if skill.tag != ElementalSkill || skill.element != Anemo || skill.multiplier < 537.19 || skill.multiplier > 537.21 {
+		t.Errorf("unexpected Xiao skill at level 13: %+v", skill)
+	}
+
+	var unknownKey *UnknownKeyError
+	if _, err := TalentAttack("Xiao", "Burst", 10); !errors.As(err, &unknownKey) {
+		t.Errorf("expected an UnknownKeyError, got %v", err)
+	}
+	var invalidValue *InvalidValueError
+	if _, err := TalentAttack("Xiao", "Skill", 16); !errors.As(err, &invalidValue) {
+		t.Errorf("expected an InvalidValueError, got %v", err)
+	}
+	if _, err := TalentAttack("Amber", "Normal1", 10); !errors.As(err, &invalidValue) {
+		t.Errorf("expected an InvalidValueError for a character without talent data, got %v", err)
+	}
+	if _, err := TalentAttack("Paimon", "Normal1", 10); !errors.As(err, &unknownKey) {
+		t.Errorf("expected an UnknownKeyError for an unknown character, got %v", err)
+	}
+
+	triKarma, _ := TalentAttack("Nahida", "TriKarmaPurification", 10)
+	if triKarma.element != Dendro || !nearlyEqual(triKarma.multiplier, 185.76) ||
+		triKarma.secondaryStat != ElementalMastery || !nearlyEqual(triKarma.secondaryMultiplier, 371.52) {
+		t.Errorf("unexpected Nahida Tri-Karma Purification at level 10: %+v", triKarma)
+	}
+	kukiBurst, _ := TalentAttack("KukiShinobu", "GyoeiKariyama", 1)
+	if kukiBurst.offensiveStat != HP || kukiBurst.multiplier != 3.6 || kukiBurst.secondaryMultiplier != 0 {
+		t.Errorf("unexpected Kuki burst at level 1: %+v", kukiBurst)
+	}
+	c := optimizationConfig{character: character{level: 90, bonusStats: map[stat]float32{}}, enemy: standardEnemy}
+	stats := map[stat]float32{ATK: 1000, ElementalMastery: 500}
+	if base := c.attackDamage(triKarma, stats).baseDamage; !nearlyEqual(base, 1857.6+1857.6) {
+		t.Errorf("expected the base damage to add up both scaling stats, got %v", base)
+	}
+}
+
+func TestTalentBuff(t *testing.T) {
+	burst, err := TalentBuff("Xiao", "BaneOfAllEvil", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []stat{NormalAttackDMG, ChargedAttackDMG, PlungeDMG} {
+		if burst.buff()[s] != 95.2 {
+			t.Errorf("expected 95.2%% %v from Xiao's burst at level 10, got %v", s, burst.buff()[s])
+		}
+	}
+	if burst.buff()[SkillDMG] != 0 {
+		t.Errorf("Xiao's burst shouldn't buff his skill")
+	}
+
+	var unknownKey *UnknownKeyError
+	if _, err := TalentBuff("Xiao", "Skill", 10); !errors.As(err, &unknownKey) {
+		t.Errorf("expected an UnknownKeyError, got %v", err)
+	}
+	var invalidValue *InvalidValueError
+	for _, level := range []int{0, 16} {
+		if _, err := TalentBuff("Xiao", "BaneOfAllEvil", level); !errors.As(err, &invalidValue) {
+			t.Errorf("expected an InvalidValueError for talent level %d, got %v", level, err)
+		}
+	}
+	if _, err := TalentBuff("Amber", "BaneOfAllEvil", 10); !errors.As(err, &invalidValue) {
+		t.Errorf("expected an InvalidValueError for a character without talent buffs, got %v", err)
+	}
+}
+
+func TestRotation(t *testing.T) {
+	plunge := attack{tag: PlungeAttack, element: Anemo, offensiveStat: ATK, multiplier: 400}
+	skill := attack{tag: ElementalSkill, element: Anemo, offensiveStat: ATK, multiplier: 450}
+	c := optimizationConfig{
+		character: character{level: 90, baseAtk: 100, bonusStats: map[stat]float32{CritRate: 95, PlungeDMG: 100}},
+		enemy:     standardEnemy,
+	}
+	c.rotation = singleHit(plunge)
+	plungeDmg := c.calculateTargetValue().average
+	c.rotation = singleHit(skill)
+	skillDmg := c.calculateTargetValue().average
+
+	c.rotation = rotation{{attack: plunge, count: 10}, {attack: skill, count: 2.5}}
+	expected := 10*plungeDmg + 2.5*skillDmg
+	if total := c.calculateTargetValue().average; total < expected-0.01 || total > expected+0.01 {
+		t.Errorf("expected a total rotation damage of %v, got %v", expected, total)
+	}
+	if skillDmg >= plungeDmg {
+		t.Errorf("the plunge DMG bonus should not apply to the skill")
+	}
+}
+
+func TestBuffProviders(t *testing.T) {
+	bennett, _ := CharacterByKey("Bennett", 90)
+	bennett.weapon, _ = WeaponByKey("SapwoodBlade", 90, 1, condition{})
+	bennettBuff, err := NewBennettBurst(bennett, 13, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if atk := bennettBuff.buff()[ATK]; atk < 1050 || atk > 1051.5 {
+		t.Errorf("expected around 1050.8 ATK from Bennett, got %v", atk)
+	}
+
+	faruzan, _ := CharacterByKey("Faruzan", 90)
+	faruzan.weapon, _ = WeaponByKey("FavoniusWarbow", 90, 1, condition{})
+	faruzanBurst, err := NewFaruzanBurst(faruzan, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	faruzanBuff := faruzanBurst.buff()
+	if faruzanBuff[AnemoDMG] < 32.39 || faruzanBuff[AnemoDMG] > 32.41 || faruzanBuff[AnemoDMGIncrease] < 207.5 || faruzanBuff[AnemoDMGIncrease] > 208.5 {
+		t.Errorf("unexpected Faruzan buff: %v", faruzanBuff)
+	}
+
+	// the buff follows the provider's stats
+	kazuha := character{level: 90, bonusStats: map[stat]float32{ElementalMastery: 1000}}
+	a4, err := NewKazuhaA4(kazuha, Pyro)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dmg := a4.buff()[PyroDMG]; dmg < 39.99 || dmg > 40.01 {
+		t.Errorf("expected a 40%% Pyro DMG bonus from Kazuha, got %v", dmg)
+	}
+	kazuha.bonusStats[ElementalMastery] = 500
+	if dmg := a4.buff()[PyroDMG]; dmg < 19.99 || dmg > 20.01 {
+		t.Errorf("expected a 20%% Pyro DMG bonus from Kazuha, got %v", dmg)
+	}
+
+	// A4 passives need ascension phase 4
+	lowKazuha, _ := CharacterByKeyAndAscension("KaedeharaKazuha", 60, 3)
+	if lowA4, _ := NewKazuhaA4(lowKazuha, Pyro); len(lowA4.buff()) != 0 {
+		t.Errorf("expected no buff from Kazuha's A4 at ascension 3, got %v", lowA4.buff())
+	}
+	lowFaruzan, _ := CharacterByKeyAndAscension("Faruzan", 60, 3)
+	if lowBurst, _ := NewFaruzanBurst(lowFaruzan, 10); lowBurst.buff()[AnemoDMGIncrease] != 0 || lowBurst.buff()[AnemoDMG] == 0 {
+		t.Errorf("expected only the burst's Anemo DMG bonus from Faruzan at ascension 3, got %v", lowBurst.buff())
+	}
+
+	c := character{level: 90, baseAtk: 100, bonusStats: map[stat]float32{}, buffs: []buffProvider{
+		elementalResonance(Pyro),
+		bennettBurst{bennett: character{baseAtk: 100}, talentLevel: 1},
+		a4,
+	}}
+	stats := c.stats()
+	if stats[ATK] < 180.99 || stats[ATK] > 181.01 || stats[PyroDMG] < 19.99 || stats[PyroDMG] > 20.01 {
+		t.Errorf("expected 181 ATK and 20%% Pyro DMG bonus, got %v and %v", stats[ATK], stats[PyroDMG])
+	}
+	if len(elementalResonance(Anemo).buff()) != 0 {
+		t.Errorf("the Anemo resonance should not change any stat")
+	}
+
+	var invalidValue *InvalidValueError
+	for _, level := range []int{0, MaxTalentLevel + 1} {
+		if _, err := NewBennettBurst(bennett, level, false); !errors.As(err, &invalidValue) {
+			t.Errorf("expected an InvalidValueError for Bennett's burst at level %d, got %v", level, err)
+		}
+		if _, err := NewFaruzanBurst(faruzan, level); !errors.As(err, &invalidValue) {
+			t.Errorf("expected an InvalidValueError for Faruzan's burst at level %d, got %v", level, err)
+		}
+	}
+	if _, err := NewKazuhaA4(kazuha, Geo); !errors.As(err, &invalidValue) {
+		t.Errorf("expected an InvalidValueError for a Geo swirl, got %v", err)
+	}
+}
+
+func TestStatPipeline(t *testing.T) {
+	c := character{
+		level:      90,
+		baseHP:     10000,
+		baseAtk:    100,
+		baseDef:    500,
+		bonusStats: map[stat]float32{HPP: 50, HP: 1000, ATKP: 100, ATK: 50, EnergyRecharge: 100, CritRate: 10},
+		weapon:     weapon{baseAtk: 500, stats: map[stat]float32{ATKP: 20}},
+		conversions: []conversion{
+			func(s map[stat]float32) map[stat]float32 { return map[stat]float32{ATK: s[EnergyRecharge]} },
+			func(s map[stat]float32) map[stat]float32 { return map[stat]float32{CritRate: s[ATK] / 100} },
+		},
+	}
+	p := c.statStages()
+
+	if p.base[ATK] != 600 || p.base[BaseATK] != 600 || p.base[CritRate] != 5 || p.base[HP] != 10000 {
+		t.Errorf("unexpected base stage: %v", p.base)
+	}
+	if p.percent[ATK] != 600*2.2 || p.percent[HP] != 15000 || p.percent[CritRate] != 15 {
+		t.Errorf("unexpected percent stage: %v", p.percent)
+	}
+	if p.flat[ATK] != 600*2.2+50 || p.flat[HP] != 16000 {
+		t.Errorf("unexpected flat stage: %v", p.flat)
+	}
+	// the CRIT Rate conversion doesn't see the ATK from the ER conversion
+	if p.conversions[ATK] != 200 || p.conversions[CritRate] != p.flat[ATK]/100 {
+		t.Errorf("unexpected conversions: %v", p.conversions)
+	}
+	if p.final[ATK] != p.flat[ATK]+200 || p.final[CritRate] != 15+p.flat[ATK]/100 {
+		t.Errorf("unexpected final stage: %v", p.final)
+	}
+	stats := c.stats()
+	for stat, v := range p.final {
+		if stats[stat] != v {
+			t.Errorf("stats should return the final stage, got %v %v instead of %v", stats[stat], stat, v)
+		}
+	}
+	if fixed := c.withFixedBonus().stats(); fixed[ATK] != stats[ATK] || fixed[CritRate] != stats[CritRate] {
+		t.Errorf("merging the fixed bonus should not change the stats")
+	}
+
+	c.weapon, _ = WeaponByKey("StaffOfHoma", 90, 1, condition{})
+	if atk := c.statStages().conversions[ATK]; atk < 200+18000*0.008-0.01 || atk > 200+18000*0.008+0.01 {
+		t.Errorf("expected Homa to convert the HP from the flat stage, got %v ATK", atk)
+	}
+
+	raiden, _ := CharacterByKey("RaidenShogun", 90)
+	raiden.bonusStats[EnergyRecharge] = 150
+	if dmg := raiden.statStages().conversions[ElectroDMG]; dmg < 59.99 || dmg > 60.01 {
+		t.Errorf("expected 60%% Electro DMG bonus from Raiden's passive, got %v", dmg)
+	}
+	raiden, _ = CharacterByKey("RaidenShogun", 40)
+	raiden.bonusStats[EnergyRecharge] = 150
+	if dmg := raiden.stats()[ElectroDMG]; dmg != 0 {
+		t.Errorf("Raiden's passive should not be unlocked at level 40, got %v Electro DMG bonus", dmg)
+	}
+
+	nahida, _ := CharacterByKey("Nahida", 90)
+	nahida.bonusStats[ElementalMastery] = 800
+	if conv := nahida.statStages().conversions; conv[SkillDMG] < 59.99 || conv[SkillDMG] > 60.01 || conv[SkillCritRate] < 17.99 || conv[SkillCritRate] > 18.01 {
+		t.Errorf("unexpected conversions from Nahida's passive: %v", conv)
+	}
+}
+
+func TestDamageBreakdown(t *testing.T) {
+	plunge := attack{tag: PlungeAttack, element: Anemo, offensiveStat: ATK, multiplier: 400}
+	swirl := attack{tag: ReactionDamage, element: Pyro, reaction: Swirl}
+	c := optimizationConfig{
+		character: character{level: 90, baseAtk: 1000, bonusStats: map[stat]float32{CritRate: 45, AnemoDMG: 50}},
+		rotation:  rotation{{attack: plunge, count: 2}, {attack: swirl, count: 1}},
+		enemy:     standardEnemy,
+	}
+	b := c.calculateTargetValue()
+	if len(b.hits) != 2 || b.stats[ATK] != 1000 {
+		t.Fatalf("unexpected breakdown: %+v", b)
+	}
+	h := b.hits[0]
+	if h.baseDamage != 4000 || h.dmgBonusMultiplier != 1.5 || h.count != 2 {
+		t.Errorf("unexpected plunge breakdown: %+v", h)
+	}
+	nonCrit := h.baseDamage * h.dmgBonusMultiplier * h.defMultiplier * h.resMultiplier * h.reactionMultiplier
+	if h.nonCrit != nonCrit || h.crit != nonCrit*1.5 || h.average != nonCrit*h.critMultiplier {
+		t.Errorf("the plunge damage doesn't match its multipliers: %+v", h)
+	}
+	s := b.hits[1]
+	if s.crit != s.nonCrit || s.defMultiplier != 1 || s.nonCrit != s.baseDamage*s.resMultiplier*s.reactionMultiplier {
+		t.Errorf("unexpected swirl breakdown: %+v", s)
+	}
+	if b.average != 2*h.average+s.average || b.crit != 2*h.crit+s.crit || b.nonCrit != 2*h.nonCrit+s.nonCrit {
+		t.Errorf("the rotation totals don't match its hits: %+v", b)
+	}
+
+	// findBest returns the breakdown of the winning build
+	good := gladiatorPiece(SlotFlower, HP, CritRate, 10)
+	c.artifacts = []*Artifact{
+		gladiatorPiece(SlotFlower, HP, DEF, 10), good, gladiatorPiece(SlotPlume, ATK, DEF, 10), gladiatorPiece(SlotSands, ATKP, DEF, 10),
+		gladiatorPiece(SlotGoblet, AnemoDMG, DEF, 10), gladiatorPiece(SlotCirclet, CritDmg, DEF, 10),
+	}
+	build, best, err := c.findBest(nil, func(map[artifactSlot]*Artifact) bool { return true })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if build[SlotFlower] != good {
+		t.Errorf("expected the flower with CRIT Rate to win")
+	}
+	c.character.artifacts = build
+	if expected := c.calculateTargetValue(); best.average != expected.average || best.stats[CritRate] != expected.stats[CritRate] {
+		t.Errorf("expected the breakdown of the winning build, got %+v", best)
+	}
+}
+
+func TestCritExpectation(t *testing.T) {
+	c := optimizationConfig{
+		character: character{level: 90, baseAtk: 1000, bonusStats: map[stat]float32{CritRate: -5, CritDmg: 50}},
+		rotation:  singleHit(attack{tag: NormalAttack, element: Physical, offensiveStat: ATK, multiplier: 100}),
+		enemy:     standardEnemy,
+	}
+	tests := []struct {
+		critRate        float32
+		expectedAverage float32 // as a multiple of the non-crit damage
+	}{
+		{-5, 1}, {-50, 1}, {45, 1.5}, {95, 2}, {200, 2},
+	}
+	for _, test := range tests {
+		c.character.bonusStats[CritRate] = test.critRate
+		b := c.calculateTargetValue()
+		if b.nonCrit <= 0 || b.crit != 2*b.nonCrit {
+			t.Errorf("unexpected non-crit and crit damage: %v %v", b.nonCrit, b.crit)
+		}
+		if ratio := b.average / b.nonCrit; ratio < test.expectedAverage-0.0001 || ratio > test.expectedAverage+0.0001 {
+			t.Errorf("expected an average of x%v the non-crit damage with %v%% CRIT Rate, got x%v", test.expectedAverage, 5+test.critRate, ratio)
+		}
+	}
+
+	c.character.bonusStats[CritRate] = 45
+	c.rotation[0].count = 3
+	b := c.calculateTargetValue()
+	expected := 3 * 0.25 * b.hits[0].nonCrit * b.hits[0].nonCrit
+	if b.variance < expected*0.9999 || b.variance > expected*1.0001 {
+		t.Errorf("expected a variance of %v, got %v", expected, b.variance)
+	}
+
+	// one-shot content prefers CRIT DMG over a consistent CRIT Rate
+	consistent := gladiatorPiece(SlotFlower, HP, CritRate, 45)
+	oneShot := gladiatorPiece(SlotFlower, HP, CritDmg, 150)
+	c.character.bonusStats = map[stat]float32{}
+	c.artifacts = []*Artifact{
+		consistent, oneShot, gladiatorPiece(SlotPlume, ATK, DEF, 1), gladiatorPiece(SlotSands, ATKP, DEF, 1),
+		gladiatorPiece(SlotGoblet, PhysDMG, DEF, 1), gladiatorPiece(SlotCirclet, HPP, DEF, 1),
+	}
+	all := func(map[artifactSlot]*Artifact) bool { return true }
+	if build, _, err := c.findBest(nil, all); err != nil || build[SlotFlower] != consistent {
+		t.Errorf("expected the CRIT Rate flower to have the highest average damage")
+	}
+	c.objective = CritDamage
+	if build, best, err := c.findBest(nil, all); err != nil || build[SlotFlower] != oneShot || best.value(CritDamage) != best.crit {
+		t.Errorf("expected the CRIT DMG flower to have the highest crit damage")
+	}
+}
+
+// gladiatorPiece returns a fully upgraded Gladiator artifact with a single substat
+func gladiatorPiece(slot artifactSlot, main stat, sub stat, value float32) *Artifact {
+	return &Artifact{Set: "GladiatorsFinale", Slot: slot, MainStat: main, Rarity: 5, Level: 20,
+		SubStats: [MaxSubstats]*ArtifactSubstat{{Stat: sub, Rolls: 1, Value: value}}}
+}
+
+// raidenTestConfig returns the config of a level 90 Raiden with Engulfing Lightning using her burst once.
+// Her artifacts are the given amount of Emblem and Shimenawa domain drops from the seed,
+// each followed by a Gladiator piece if withGladiator is true.
+func raidenTestConfig(t testing.TB, seed int64, drops int, withGladiator bool) optimizationConfig {
+	t.Helper()
+	gen := NewSeededGenerator(seed)
+	var artis []*Artifact
+	for i := 0; i < drops; i++ {
+		artis = append(artis, gen.RandomArtifactFromDomain("EmblemOfSeveredFate", "ShimenawasReminiscence"))
+		if withGladiator {
+			artis = append(artis, gen.RandomArtifactOfSet("GladiatorsFinale", DomainBase4Chance))
+		}
+	}
+
+	c, err := CharacterByKey("RaidenShogun", 90)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.weapon, err = WeaponByKey("EngulfingLightning", 90, 1, condition{}); err != nil {
+		t.Fatal(err)
+	}
+	burst, err := TalentAttack("RaidenShogun", "MusouNoHitotachi", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return optimizationConfig{character: c, rotation: singleHit(burst), enemy: standardEnemy, artifacts: artis}
+}
+
+// nearlyEqual returns true if the value is within 1e-5 of the expected one, relatively.
+// Builds found by different searches can differ in the last digits.
+func nearlyEqual(value, expected float32) bool {
+	diff := value - expected
+	return diff >= -expected*1e-5 && diff <= expected*1e-5
+}
+
+// bruteForceBest returns the value of the best build, checking every combination
+func bruteForceBest(c optimizationConfig, buildFilter func(map[artifactSlot]*Artifact) bool) float32 {
+	values := bruteForceValues(c, buildFilter)
+	if len(values) == 0 {
+		return 0
+	}
+	return values[0]
+}
+
+// bruteForceValues returns the values of every build, the best first
+func bruteForceValues(c optimizationConfig, buildFilter func(map[artifactSlot]*Artifact) bool) []float32 {
+	var values []float32
+	for _, r := range bruteForceResults(c, buildFilter) {
+		values = append(values, r.value)
+	}
+	return values
+}
+
+// bruteForceResults returns every build with its value, the best first
+func bruteForceResults(c optimizationConfig, buildFilter func(map[artifactSlot]*Artifact) bool) []buildResult {
+	bySlot := artifactsBySlot(c.artifacts)
+	var results []buildResult
+	for _, flower := range bySlot[SlotFlower] {
+		for _, plume := range bySlot[SlotPlume] {
+			for _, sands := range bySlot[SlotSands] {
+				for _, goblet := range bySlot[SlotGoblet] {
+					for _, circlet := range bySlot[SlotCirclet] {
+						build := map[artifactSlot]*Artifact{SlotFlower: flower, SlotPlume: plume, SlotSands: sands, SlotGoblet: goblet, SlotCirclet: circlet}
+						if buildFilter != nil && !buildFilter(build) {
+							continue
+						}
+						c.character.artifacts = build
+						results = append(results, buildResult{build: build, value: c.calculateTargetValue().value(c.objective)})
+					}
+				}
+			}
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].value > results[j].value })
+	return results
+}
+
+func TestFindBestPruning(t *testing.T) {
+	config := raidenTestConfig(t, 42, 20, true)
+	skill, err := TalentAttack("RaidenShogun", "CoordinatedAttack", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.rotation = append(config.rotation, rotationHit{attack: skill, count: 10})
+	fourEmblem := func(build map[artifactSlot]*Artifact) bool {
+		count := 0
+		for _, art := range build {
+			if art.Set == "EmblemOfSeveredFate" {
+				count++
+			}
+		}
+		return count >= 4
+	}
+
+	for _, objective := range []damageObjective{AverageDamage, CritDamage} {
+		config.objective = objective
+		for _, filter := range []func(map[artifactSlot]*Artifact) bool{nil, fourEmblem} {
+			build, best, err := config.findBest(nil, filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			expected := bruteForceBest(config, filter)
+			if !nearlyEqual(best.value(objective), expected) {
+				t.Errorf("expected the pruned search to find %v, got %v", expected, best.value(objective))
+			}
+			if filter != nil && !filter(build) {
+				t.Errorf("the best build doesn't pass the filter")
+			}
+		}
+	}
+}
+
+func TestFindTopEverySet(t *testing.T) {
+	// pieces of every set, so the bounds go through every kind of set combination
+	config := raidenTestConfig(t, 5, 0, false)
+	gen := NewSeededGenerator(5)
+	for i := 0; i < 60; i++ {
+		config.artifacts = append(config.artifacts, gen.RandomArtifact(StrongboxBase4Chance))
+	}
+	skill, err := TalentAttack("RaidenShogun", "CoordinatedAttack", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.rotation = append(config.rotation, rotationHit{attack: skill, count: 10})
+
+	expected := bruteForceValues(config, nil)
+	results, err := config.findTop(5, slotCount, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, r := range results {
+		if !nearlyEqual(r.value, expected[i]) {
+			t.Errorf("expected the build number %d to deal %v, got %v", i+1, expected[i], r.value)
+		}
+	}
+}
+
+// BenchmarkFindBestLargeInventory searches the best build among 1500 max level pieces, the size of a well played account
+func BenchmarkFindBestLargeInventory(b *testing.B) {
+	config := raidenTestConfig(b, 9, 0, false)
+	gen := NewSeededGenerator(9)
+	for i := 0; i < 1500; i++ {
+		config.artifacts = append(config.artifacts, gen.RandomArtifact(StrongboxBase4Chance))
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := config.findBest(nil, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestDominanceWithStatConstraints(t *testing.T) {
+	all := func(map[artifactSlot]*Artifact) bool { return true }
+	otherSlots := []*Artifact{
+		gladiatorPiece(SlotPlume, ATK, DEF, 1), gladiatorPiece(SlotSands, ATKP, DEF, 1),
+		gladiatorPiece(SlotGoblet, ElectroDMG, DEF, 1), gladiatorPiece(SlotCirclet, CritDmg, DEF, 1),
+	}
+	// finalStat returns the final stat of the config's character wearing the flower and the other slots
+	finalStat := func(c optimizationConfig, flower *Artifact, s stat) float32 {
+		c.character.artifacts = map[artifactSlot]*Artifact{SlotFlower: flower}
+		for _, art := range otherSlots {
+			c.character.artifacts[art.Slot] = art
+		}
+		return c.character.stats()[s]
+	}
+
+	// the ATK% flower deals more damage, but only the other one stays under the max ATK
+	raiden := raidenTestConfig(t, 1, 0, false)
+	underCap := gladiatorPiece(SlotFlower, HP, CritRate, 10)
+	overCap := gladiatorPiece(SlotFlower, HP, CritRate, 10)
+	overCap.SubStats[1] = &ArtifactSubstat{Stat: ATKP, Rolls: 1, Value: 40}
+	raiden.artifacts = append([]*Artifact{overCap, underCap}, otherSlots...)
+	raiden.constraints.maxStats = map[stat]float32{ATK: finalStat(raiden, underCap, ATK)}
+
+	// the HP% flower deals more damage, but only the ATK% one reaches the min ATK
+	kuki, err := CharacterByKey("KukiShinobu", 90)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kuki.weapon, err = WeaponByKey("FavoniusSword", 90, 1, condition{}); err != nil {
+		t.Fatal(err)
+	}
+	burst, err := TalentAttack("KukiShinobu", "GyoeiKariyama", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	moreHP := gladiatorPiece(SlotFlower, HP, HPP, 10)
+	moreATK := gladiatorPiece(SlotFlower, HP, ATKP, 40)
+	kukiConfig := optimizationConfig{character: kuki, rotation: singleHit(burst), artifacts: append([]*Artifact{moreHP, moreATK}, otherSlots...)}
+	kukiConfig.constraints.minStats = map[stat]float32{ATK: finalStat(kukiConfig, moreATK, ATK)}
+
+	for _, test := range []struct {
+		name     string
+		config   optimizationConfig
+		expected *Artifact
+	}{
+		{"max ATK", raiden, underCap},
+		{"min ATK", kukiConfig, moreATK},
+	} {
+		for _, filter := range []func(map[artifactSlot]*Artifact) bool{nil, all} {
+			build, _, err := test.config.findBest(nil, filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if build[SlotFlower] != test.expected {
+				t.Errorf("%s: expected the only flower that meets the constraints to be picked, with a build filter: %v", test.name, filter != nil)
+			}
+		}
+	}
+}
+
+func TestDominanceWithCappedConversion(t *testing.T) {
+	// emblem returns a fully upgraded Emblem artifact with the given main stat value and substats
+	emblem := func(slot artifactSlot, main stat, mainValue float32, subs ...*ArtifactSubstat) *Artifact {
+		art := &Artifact{Set: "EmblemOfSeveredFate", Slot: slot, MainStat: main, MainStatValue: mainValue, Rarity: 5, Level: 20}
+		copy(art.SubStats[:], subs)
+		return art
+	}
+	sub := func(s stat, value float32) *ArtifactSubstat {
+		return &ArtifactSubstat{Stat: s, Rolls: 1, Value: value}
+	}
+
+	// Emblem's Burst DMG stops growing at 300% ER, which only the ER sands reach. With the ATK% sands, the ER flower
+	// still raises the damage over the ATK one.
+	c, err := CharacterByKey("RaidenShogun", 60)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.weapon, err = WeaponByKey("TheCatch", 90, 1, condition{}); err != nil {
+		t.Fatal(err)
+	}
+	burst, err := TalentAttack("RaidenShogun", "MusouNoHitotachi", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := optimizationConfig{character: c, rotation: singleHit(burst), enemy: standardEnemy, artifacts: []*Artifact{
+		emblem(SlotFlower, HP, 4780, sub(EnergyRecharge, 20), sub(CritRate, 3)),
+		emblem(SlotFlower, HP, 4780, sub(CritRate, 3), sub(ATK, 1)),
+		emblem(SlotPlume, ATK, 311, sub(EnergyRecharge, 20)),
+		emblem(SlotSands, EnergyRecharge, 51.8),
+		emblem(SlotSands, ATKP, 46.6),
+		emblem(SlotGoblet, ElectroDMG, 46.6, sub(EnergyRecharge, 30)),
+		emblem(SlotCirclet, CritDmg, 62.2),
+	}}
+
+	expected := bruteForceBest(config, nil)
+	for _, filter := range []func(map[artifactSlot]*Artifact) bool{nil, func(map[artifactSlot]*Artifact) bool { return true }} {
+		_, best, err := config.findBest(nil, filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !nearlyEqual(best.average, expected) {
+			t.Errorf("expected the best build to deal %v, got %v, with a build filter: %v", expected, best.average, filter != nil)
+		}
+	}
+}
+
+func TestParallelSearch(t *testing.T) {
+	config := raidenTestConfig(t, 7, 30, false)
+	artis := config.artifacts
+
+	// every run returns the same build
+	first, _, err := config.findBest(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		if build, _, err := config.findBest(nil, nil); err != nil || !reflect.DeepEqual(build, first) {
+			t.Fatalf("expected the same build on every run, got %v and %v", first, build)
+		}
+	}
+
+	lastDone, lastTotal := 0, 0
+	progress := func(done, total int) {
+		if done != lastDone+1 {
+			t.Errorf("expected progress to go one job at a time, got %d after %d", done, lastDone)
+		}
+		lastDone, lastTotal = done, total
+	}
+	if _, err := config.findTopContext(context.Background(), progress, 1, slotCount, nil, nil); err != nil {
+		t.Error(err)
+	}
+	if lastDone == 0 || lastDone != lastTotal {
+		t.Errorf("expected progress to end with every job done, got %d of %d", lastDone, lastTotal)
+	}
+
+	cancelledCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := config.findTopContext(cancelledCtx, nil, 1, slotCount, nil, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the search to be cancelled, got %v", err)
+	}
+
+	// the parallel RV search finds the same build as the sequential one, ties included
+	rvMultipliers := map[stat]float32{CritRate: 1, CritDmg: 1}
+	bySlot := artifactsBySlot(artis)
+	var expected map[artifactSlot]*Artifact
+	var expectedRV float32
+	for _, flower := range bySlot[SlotFlower] {
+		for _, plume := range bySlot[SlotPlume] {
+			for _, sands := range bySlot[SlotSands] {
+				for _, goblet := range bySlot[SlotGoblet] {
+					for _, circlet := range bySlot[SlotCirclet] {
+						build := map[artifactSlot]*Artifact{SlotFlower: flower, SlotPlume: plume, SlotSands: sands, SlotGoblet: goblet, SlotCirclet: circlet}
+						rv := flower.subsQuality(rvMultipliers) + plume.subsQuality(rvMultipliers) + sands.subsQuality(rvMultipliers) +
+							goblet.subsQuality(rvMultipliers) + circlet.subsQuality(rvMultipliers)
+						if rv > expectedRV {
+							expected, expectedRV = build, rv
+						}
+					}
+				}
+			}
+		}
+	}
+	if build, rv, err := findHighestRV(artis, rvMultipliers, buildConstraints{}, nil, nil); err != nil || rv != expectedRV || !reflect.DeepEqual(build, expected) {
+		t.Errorf("expected %v RV from %v, got %v from %v", expectedRV, expected, rv, build)
+	}
+	if _, _, err := findHighestRVContext(cancelledCtx, nil, artis, rvMultipliers, buildConstraints{}, nil, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the RV search to be cancelled, got %v", err)
+	}
+}
+
+func TestTopBuilds(t *testing.T) {
+	pieces := make([]*Artifact, 10)
+	for i := range pieces {
+		pieces[i] = &Artifact{}
+	}
+	buildOf := func(arts ...*Artifact) map[artifactSlot]*Artifact {
+		build := map[artifactSlot]*Artifact{}
+		for slot, art := range arts {
+			build[artifactSlot(slot)] = art
+		}
+		return build
+	}
+
+	top := newTopBuilds(2)
+	for i, value := range []float32{3, 1, 5, 4} {
+		top.add(buildResult{build: buildOf(pieces[i]), value: value})
+	}
+	if sorted := top.sorted(); len(sorted) != 2 || sorted[0].value != 5 || sorted[1].value != 4 || top.threshold() != 4 {
+		t.Errorf("expected to keep the builds with 5 and 4, got %v", sorted)
+	}
+
+	first := buildOf(pieces[0])
+	top.add(buildResult{build: first, value: 6})
+	first[SlotFlower] = pieces[9]
+	if top.sorted()[0].build[SlotFlower] != pieces[0] {
+		t.Errorf("the kept builds should be copies")
+	}
+
+	// the second build shares 4 pieces with the first one, the third shares 3 with the first one and 4 with the second one
+	sorted := []buildResult{
+		{build: buildOf(pieces[0], pieces[1], pieces[2], pieces[3], pieces[4]), value: 11},
+		{build: buildOf(pieces[0], pieces[1], pieces[2], pieces[3], pieces[5]), value: 10},
+		{build: buildOf(pieces[0], pieces[1], pieces[2], pieces[6], pieces[5]), value: 9},
+		{build: buildOf(pieces[7], pieces[8], pieces[2], pieces[6], pieces[5]), value: 8},
+	}
+	d := diversity{picked: []map[artifactSlot]*Artifact{sorted[0].build}, maxShared: 3}
+	if d.allows(sorted[1].build) || !d.allows(sorted[2].build) || !d.allows(sorted[3].build) {
+		t.Errorf("expected only the builds sharing at most 3 pieces with the first one to be allowed")
+	}
+	d.picked = append(d.picked, sorted[2].build)
+	if !d.allows(sorted[3].build) {
+		t.Errorf("expected the build sharing 3 pieces with the second picked one to be allowed")
+	}
+	var cands candidates
+	for slot, art := range sorted[0].build {
+		cands[slot] = []*Artifact{art}
+	}
+	d.picked = d.picked[:1]
+	if _, ok := d.restrict(cands); ok {
+		t.Errorf("expected no allowed build from the pieces of a picked build")
+	}
+	cands[SlotFlower] = append(cands[SlotFlower], pieces[7])
+	if _, ok := d.restrict(cands); ok {
+		t.Errorf("expected no allowed build with another flower, as the other 4 pieces are shared")
+	}
+	// with 3 pieces shared, the flower and plume of the picked build can't be used
+	cands[SlotPlume] = append(cands[SlotPlume], pieces[8])
+	restricted, ok := d.restrict(cands)
+	if !ok || len(restricted[SlotFlower]) != 1 || restricted[SlotFlower][0] != pieces[7] || len(restricted[SlotPlume]) != 1 || restricted[SlotPlume][0] != pieces[8] {
+		t.Errorf("expected only the other flower and plume to be left, got %v", restricted)
+	}
+
+	config := raidenTestConfig(t, 3, 25, false)
+
+	expected := bruteForceValues(config, nil)
+	results, err := config.findTop(5, slotCount, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 5 {
+		t.Fatalf("expected 5 builds, got %d", len(results))
+	}
+	for i, r := range results {
+		if !nearlyEqual(r.value, expected[i]) {
+			t.Errorf("expected the build number %d to deal %v, got %v", i+1, expected[i], r.value)
+		}
+		if r.breakdown.value(config.objective) != r.value {
+			t.Errorf("the value of a build should be the one of its breakdown")
+		}
+	}
+
+	// the diverse builds are the ones picked greedily from every build, best first
+	all := bruteForceResults(config, nil)
+	for _, maxShared := range []int{3, 1} {
+		var expected []buildResult
+		for _, r := range all {
+			diverse := true
+			for _, e := range expected {
+				diverse = diverse && sharedPieces(e.build, r.build) <= maxShared
+			}
+			if diverse && len(expected) < 5 {
+				expected = append(expected, r)
+			}
+		}
+		results, err = config.findTop(5, maxShared, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != len(expected) {
+			t.Fatalf("expected %d builds sharing at most %d pieces, got %d", len(expected), maxShared, len(results))
+		}
+		for i, r := range results {
+			if !nearlyEqual(r.value, expected[i].value) {
+				t.Errorf("expected the build number %d sharing at most %d pieces to deal %v, got %v", i+1, maxShared, expected[i].value, r.value)
+			}
+			for j := 0; j < i; j++ {
+				if shared := sharedPieces(results[j].build, r.build); shared > maxShared {
+					t.Errorf("builds %d and %d share %d pieces", j+1, i+1, shared)
+				}
+			}
+		}
+	}
+}
+
+func TestTopBuildsNotEnoughDiverse(t *testing.T) {
+	config := raidenTestConfig(t, 5, 125, false)
+	// two flowers can't make three builds sharing no piece
+	var artis []*Artifact
+	flowers := 0
+	for _, arti := range config.artifacts {
+		if arti.Slot == SlotFlower {
+			if flowers == 2 {
+				continue
+			}
+			flowers++
+		}
+		artis = append(artis, arti)
+	}
+	config.artifacts = artis
+
+	results, err := config.findTop(3, 0, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 builds sharing no piece, got %d", len(results))
+	}
+	if shared := sharedPieces(results[0].build, results[1].build); shared > 0 {
+		t.Errorf("the builds share %d pieces", shared)
+	}
+}
+
+func TestBuildConstraints(t *testing.T) {
+	config := raidenTestConfig(t, 11, 20, true)
+	artis := config.artifacts
+
+	unconstrained, _, err := config.findBest(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bySlot := artifactsBySlot(artis)
+	var lockedCirclet *Artifact
+	for _, art := range bySlot[SlotCirclet] {
+		if art != unconstrained[SlotCirclet] {
+			lockedCirclet = art
+			break
+		}
+	}
+	config.constraints = buildConstraints{
+		sets:      []setRequirement{fourPieceOf("EmblemOfSeveredFate"), twoPieceOf("EmblemOfSeveredFate", "GladiatorsFinale")},
+		minStats:  map[stat]float32{EnergyRecharge: 180},
+		maxStats:  map[stat]float32{CritRate: 70},
+		mainStats: mainStatRules{SlotSands: {EnergyRecharge, ATKP}},
+		locked:    map[artifactSlot]*Artifact{SlotCirclet: lockedCirclet},
+		excluded:  map[*Artifact]bool{unconstrained[SlotFlower]: true},
+	}
+
+	if config.constraints.allowsPiece(unconstrained[SlotFlower]) || config.constraints.allowsPiece(unconstrained[SlotCirclet]) {
+		t.Errorf("excluded pieces and the ones of locked slots should not be allowed")
+	}
+	for _, art := range bySlot[SlotSands] {
+		if allowed := config.constraints.allowsPiece(art); allowed != (art.MainStat == EnergyRecharge || art.MainStat == ATKP) {
+			t.Errorf("unexpected allowed %v sands: %v", art.MainStat, allowed)
+		}
+	}
+
+	// only 3 slots can have Emblem pieces
+	emblem := &Artifact{Set: "EmblemOfSeveredFate"}
+	gladiator := &Artifact{Set: "GladiatorsFinale"}
+	fourEmblem := buildConstraints{sets: []setRequirement{fourPieceOf("EmblemOfSeveredFate")}}
+	if fourEmblem.setsPossible(candidates{{emblem, gladiator}, {emblem}, {gladiator}, {emblem}, {gladiator}}) {
+		t.Errorf("4 Emblem pieces should not be possible from 3 slots")
+	}
+	if !config.constraints.setsPossible(candidates{{emblem, gladiator}, {emblem}, {gladiator}, {emblem}, {gladiator}}) {
+		t.Errorf("2 Emblem and 2 Gladiator pieces should be possible")
+	}
+	if rainbow := (buildConstraints{sets: []setRequirement{{}}}); !rainbow.allowsSets(map[artifactSlot]*Artifact{SlotFlower: gladiator}) {
+		t.Errorf("an empty set requirement should allow rainbow builds")
+	}
+
+	allowed := func(build map[artifactSlot]*Artifact) bool {
+		for _, art := range build {
+			if !config.constraints.allowsPiece(art) {
+				return false
+			}
+		}
+		config.character.artifacts = build
+		return config.constraints.allowsSets(build) && config.constraints.allowsStats(config.character.stats())
+	}
+	build, best, err := config.findBest(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := bruteForceBest(config, allowed)
+	if !nearlyEqual(best.average, expected) {
+		t.Errorf("expected the constrained search to find %v, got %v", expected, best.average)
+	}
+	if !allowed(build) || build[SlotCirclet] != lockedCirclet {
+		t.Errorf("the best build doesn't meet the constraints: %v", build)
+	}
+
+	rvConstraints := buildConstraints{sets: []setRequirement{fourPieceOf("EmblemOfSeveredFate")}, minStats: map[stat]float32{EnergyRecharge: 130}}
+	rvBuild, _, err := findHighestRV(artis, map[stat]float32{CritRate: 1, CritDmg: 1}, rvConstraints, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rvConstraints.allowsSets(rvBuild) || (character{artifacts: rvBuild}).stats()[EnergyRecharge] < 130 {
+		t.Errorf("the highest RV build doesn't meet the constraints: %v", rvBuild)
+	}
+
+	// locked pieces that can't be used are reported, instead of leaving their slot empty
+	var invalidValue *InvalidValueError
+	config.constraints = buildConstraints{locked: map[artifactSlot]*Artifact{SlotFlower: unconstrained[SlotFlower]}, excluded: map[*Artifact]bool{unconstrained[SlotFlower]: true}}
+	if build, _, err := config.findBest(nil, nil); build != nil || !errors.As(err, &invalidValue) {
+		t.Errorf("expected an InvalidValueError for an excluded locked piece, got %v", err)
+	}
+	config.constraints = buildConstraints{locked: map[artifactSlot]*Artifact{SlotFlower: NewSeededGenerator(1).RandomArtifactOfSlot(SlotFlower, DomainBase4Chance)}}
+	if _, _, err := findHighestRV(artis, map[stat]float32{CritRate: 1}, config.constraints, nil, nil); !errors.As(err, &invalidValue) {
+		t.Errorf("expected an InvalidValueError for a locked piece that isn't searched, got %v", err)
+	}
+	config.constraints = buildConstraints{locked: map[artifactSlot]*Artifact{SlotPlume: unconstrained[SlotFlower]}}
+	if _, err := config.findTop(3, 3, nil, nil); !errors.As(err, &invalidValue) {
+		t.Errorf("expected an InvalidValueError for a piece locked in another slot, got %v", err)
+	}
+}
+
+func TestMainStatRules(t *testing.T) {
+	config := raidenTestConfig(t, 5, 30, false)
+	artis := config.artifacts
+	unconstrainedBuild, unconstrained, err := config.findBest(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a sands main stat other than the one of the best build
+	var sandsStat stat
+	for _, art := range artifactsBySlot(artis)[SlotSands] {
+		if art.MainStat != unconstrainedBuild[SlotSands].MainStat {
+			sandsStat = art.MainStat
+			break
+		}
+	}
+	config.constraints.mainStats = mainStatRules{SlotSands: {sandsStat}, SlotCirclet: {CritRate, CritDmg}}
+	build, best, err := config.findBest(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := bruteForceBest(config, func(build map[artifactSlot]*Artifact) bool {
+		for _, art := range build {
+			if !config.constraints.mainStats.allows(art) {
+				return false
+			}
+		}
+		return true
+	})
+	if !nearlyEqual(best.average, expected) {
+		t.Errorf("expected the best build with the rules to deal %v, got %v", expected, best.average)
+	}
+	if build[SlotSands].MainStat != sandsStat || (build[SlotCirclet].MainStat != CritRate && build[SlotCirclet].MainStat != CritDmg) {
+		t.Errorf("the best build doesn't follow the main stat rules: %v", build)
+	}
+
+	// auto mode, limited to the sands main stat by the rules
+	results, err := config.findBestMainStats(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) == 0 || results[0].best.value != best.average {
+		t.Fatalf("expected the winning combination to be the best build with the rules")
+	}
+	for i, r := range results {
+		for _, slot := range []artifactSlot{SlotSands, SlotGoblet, SlotCirclet} {
+			if r.best.build[slot].MainStat != r.mainStats[slot][0] {
+				t.Errorf("the build of the combination %v uses a %v %v", r.mainStats, r.best.build[slot].MainStat, slot)
+			}
+		}
+		if i > 0 && r.best.value > results[i-1].best.value {
+			t.Errorf("expected the combinations to be sorted by value")
+		}
+	}
+
+	config.constraints.mainStats = nil
+	results, err = config.findBestMainStats(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	combos := 1
+	for _, slot := range []artifactSlot{SlotSands, SlotGoblet, SlotCirclet} {
+		mainStats := map[stat]bool{}
+		for _, art := range artifactsBySlot(artis)[slot] {
+			mainStats[art.MainStat] = true
+		}
+		combos *= len(mainStats)
+	}
+	if len(results) != combos {
+		t.Errorf("expected a result for each of the %d combinations, got %d", combos, len(results))
+	}
+	for _, r := range results {
+		config.constraints.mainStats = r.mainStats
+		_, best, err := config.findBest(nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !nearlyEqual(r.best.value, best.average) {
+			t.Errorf("expected the best build with %v to deal %v, got %v", r.mainStats, best.average, r.best.value)
+		}
+	}
+	config.constraints.mainStats = nil
+	if !nearlyEqual(results[0].best.value, unconstrained.average) {
+		t.Errorf("expected the winning combination to deal %v, got %v", unconstrained.average, results[0].best.value)
+	}
+
+	lastDone, lastTotal := 0, 0
+	progress := func(done, total int) { lastDone, lastTotal = done, total }
+	if _, err := config.findBestMainStatsContext(context.Background(), progress, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if lastDone != combos || lastTotal != combos {
+		t.Errorf("expected progress to end with the %d combinations searched, got %d of %d", combos, lastDone, lastTotal)
+	}
+	cancelledCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := config.findBestMainStatsContext(cancelledCtx, nil, nil, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the search to be cancelled, got %v", err)
+	}
+}
@@ -1,19 +1,22 @@
 package genshinartis
 
 import (
+	"context"
 	"math"
+	"runtime"
+	"sort"
 )
 
 /**
 "Simple" Optimizer, very WORK IN PROGRESS
-Right now is just made to optimize Xiao plunges
+Optimizes the total damage of a rotation of talent hits
 **/
 
 type element int
 type attackTag int
 
 const (
-	Physical = iota
+	Physical element = iota
 	Pyro
 	Hydro
 	Anemo
@@ -23,223 +26,613 @@ const (
 	Geo
 )
 
+const (
+	NormalAttack attackTag = iota
+	ChargedAttack
+	PlungeAttack
+	ElementalSkill
+	ElementalBurst
+	ReactionDamage // transformative reactions, only affected by reaction bonuses
+)
+
+var elementDMGBonusStats = map[element]stat{
+	Physical: PhysDMG,
+	Pyro:     PyroDMG,
+	Hydro:    HydroDMG,
+	Anemo:    AnemoDMG,
+	Electro:  ElectroDMG,
+	Dendro:   DendroDMG,
+	Cryo:     CryoDMG,
+	Geo:      GeoDMG,
+}
+
+var elementDMGIncreaseStats = map[element]stat{
+	Physical: PhysDMGIncrease,
+	Pyro:     PyroDMGIncrease,
+	Hydro:    HydroDMGIncrease,
+	Anemo:    AnemoDMGIncrease,
+	Electro:  ElectroDMGIncrease,
+	Dendro:   DendroDMGIncrease,
+	Cryo:     CryoDMGIncrease,
+	Geo:      GeoDMGIncrease,
+}
+
+// tagStats are the stats that only apply to attacks with a specific tag
+type tagStats struct {
+	dmgBonus    stat
+	critRate    stat
+	critDmg     stat
+	dmgIncrease stat
+}
+
+var attackTagStats = map[attackTag]tagStats{
+	NormalAttack:   {NormalAttackDMG, NormalAttackCritRate, NormalAttackCritDmg, NormalAttackDMGIncrease},
+	ChargedAttack:  {ChargedAttackDMG, ChargedAttackCritRate, ChargedAttackCritDmg, ChargedAttackDMGIncrease},
+	PlungeAttack:   {PlungeDMG, PlungeCritRate, PlungeCritDmg, PlungeDMGIncrease},
+	ElementalSkill: {SkillDMG, SkillCritRate, SkillCritDmg, SkillDMGIncrease},
+	ElementalBurst: {BurstDMG, BurstCritRate, BurstCritDmg, BurstDMGIncrease},
+}
+
 type attack struct {
-	tag           attackTag
-	element       element
-	offensiveStat stat
-	multiplier    float32
+	tag                 attackTag
+	element             element
+	reaction            reaction
+	offensiveStat       stat
+	multiplier          float32
+	secondaryStat       stat    // for attacks that scale with two stats, like Nahida's ATK and EM
+	secondaryMultiplier float32 // 0 for attacks that scale with a single stat
+}
+
+// rotationHit is an attack and the times it's used during a rotation, fractional for attacks that don't always happen
+type rotationHit struct {
+	attack attack
+	count  float32
+}
+
+// rotation is the sequence of attacks whose total damage is optimized
+type rotation []rotationHit
+
+// singleHit returns a rotation made of just one attack
+func singleHit(a attack) rotation {
+	return rotation{{attack: a, count: 1}}
 }
 
 type weapon struct {
-	baseAtk float32
-	stats   map[stat]float32
-	passive func(map[stat]float32) map[stat]float32
+	key        string // GOOD key, empty for custom weapons
+	baseAtk    float32
+	stats      map[stat]float32
+	conversion conversion
 }
 
 type character struct {
-	level      int
-	baseHP     float32
-	baseAtk    float32
-	baseDef    float32
-	bonusStats map[stat]float32
-	artifacts  map[artifactSlot]*Artifact
-	weapon     weapon
+	key           string // GOOD key, empty for custom characters
+	level         int
+	ascension     int // phase, see ascensionPhase
+	element       element
+	weaponType    weaponType
+	baseHP        float32
+	baseAtk       float32
+	baseDef       float32
+	bonusStats    map[stat]float32
+	artifacts     map[artifactSlot]*Artifact
+	setConditions map[artifactSet]condition // sets without a condition are assumed to be always active at max stacks
+	weapon        weapon
+	buffs         []buffProvider
+	conversions   []conversion // from the character's own talents
 }
 
+// artifactStats returns the sum of the stats of the equipped artifacts.
+// They are added in slot order, so a build always gets the same stats to the last digit.
 func (c character) artifactStats() map[stat]float32 {
 	s := map[stat]float32{}
-	for _, art := range c.artifacts {
+	for slot := SlotFlower; slot <= SlotCirclet; slot++ {
+		art := c.artifacts[slot]
+		if art == nil {
+			continue
+		}
 		s[art.MainStat] = s[art.MainStat] + art.MainStatValue
 		for _, subStat := range art.SubStats {
+			if subStat == nil {
+				continue
+			}
 			s[subStat.Stat] = s[subStat.Stat] + subStat.Value
 		}
 	}
 	return s
 }
 
+// stats returns the final stats of the character, see statStages
 func (c character) stats() map[stat]float32 {
-	stats := map[stat]float32{}
-	wepStats := c.weapon.stats
-	artStats := c.artifactStats()
+	return c.finalStatsWith(c.artifactStats(), artifactSetBonus(c.artifacts, c.setConditions), artifactSetConversions(c.artifacts))
+}
+
+// optimizationConfig is everything the optimizer searches with. There's no field for the allowed main stats:
+// they are a constraint like the sets or the locked pieces, see buildConstraints.mainStats, and findBestMainStats
+// is the mode that tries every combination of them and reports the winning one.
+type optimizationConfig struct {
+	character   character
+	rotation    rotation
+	objective   damageObjective
+	enemy       enemy // standardEnemy if unset
+	artifacts   []*Artifact
+	constraints buildConstraints
+}
+
+// findBest returns the build with the highest total rotation damage for the objective, and its damage breakdown, see findTop
+func (c optimizationConfig) findBest(artifactFilter func([]*Artifact) []*Artifact, buildFilter func(map[artifactSlot]*Artifact) bool) (map[artifactSlot]*Artifact, damageBreakdown, error) {
+	top, err := c.findTop(1, slotCount, artifactFilter, buildFilter)
+	if len(top) == 0 {
+		return nil, damageBreakdown{}, err
+	}
+	return top[0].build, top[0].breakdown, err
+}
 
-	// merge all the stats
-	for stat, v := range c.bonusStats {
-		stats[stat] = stats[stat] + v
+// findTop returns the n builds with the highest total rotation damage for the objective, the best first,
+// no two of them sharing more than maxShared pieces, see diversity.
+// Partial builds whose damage upper bound can't beat the builds found so far are skipped, see buildBounds.
+// The filters are called from many goroutines, and the build passed to buildFilter is reused between calls, it must not be kept.
+// It returns an error if a locked piece isn't searched, see buildConstraints.validateLocked.
+func (c optimizationConfig) findTop(n, maxShared int, artifactFilter func([]*Artifact) []*Artifact, buildFilter func(map[artifactSlot]*Artifact) bool) ([]buildResult, error) {
+	return c.findTopContext(context.Background(), nil, n, maxShared, artifactFilter, buildFilter)
+}
+
+// findTopContext is findTop split in jobs run in parallel, see runJobs.
+// If ctx is done before the search ends, it returns the best builds found so far and the error of ctx.
+// With maxShared under 5, the search runs again until n builds are picked: every run searches the best builds that share
+// at most maxShared pieces with the ones picked before, skipping the groups that can't, see diversity.restrict.
+// The progress starts over on every run.
+func (c optimizationConfig) findTopContext(ctx context.Context, progress progressFunc, n, maxShared int, artifactFilter func([]*Artifact) []*Artifact, buildFilter func(map[artifactSlot]*Artifact) bool) ([]buildResult, error) {
+	searched := c.searchedArtifacts(artifactFilter)
+	if err := c.constraints.validateLocked(searched); err != nil {
+		return nil, err
 	}
-	for stat, v := range wepStats {
-		stats[stat] = stats[stat] + v
+	bySlot := artifactsBySlot(searched)
+	if bySlot.size() == 0 || n <= 0 {
+		return nil, ctx.Err()
 	}
-	for stat, v := range artStats {
-		stats[stat] = stats[stat] + v
+	c.character = c.character.withFixedBonus()
+	bounds := newBuildBounds(c, bySlot)
+	// with diversity, at most n-1 builds are picked before another one, so one of the n dominators of a piece is in none
+	// of them and can replace it without sharing more pieces
+	if buildFilter == nil {
+		bySlot = bounds.withoutDominated(bySlot, n)
 	}
-	// set bonuses
-	for stat, v := range artifactSetBonus(c.artifacts) {
-		stats[stat] = stats[stat] + v
+	bounds.sortForSplitting(bySlot)
+	if maxShared >= slotCount {
+		return c.searchCandidates(ctx, progress, bounds, bySlot, n, diversity{}, buildFilter)
 	}
 
-	// now calculate stats with base values
-	stats[HP] = c.baseHP*(1+stats[HPP]/100) + stats[HP]
-	stats[ATK] = (c.baseAtk+c.weapon.baseAtk)*(1+stats[ATKP]/100) + stats[ATK]
-	stats[DEF] = c.baseDef*(1+stats[DEFP]/100) + stats[DEF]
-	stats[CritRate] = 5 + stats[CritRate]
-	stats[CritDmg] = 50 + stats[CritDmg]
-	stats[EnergyRecharge] = 100 + stats[EnergyRecharge]
+	// the best builds allowed by the ones picked so far are the next ones picked, as long as they allow each other
+	d := diversity{maxShared: maxShared}
+	var picked []buildResult
+	for len(picked) < n {
+		missing := n - len(picked)
+		top, err := c.searchCandidates(ctx, progress, bounds, bySlot, missing, d, buildFilter)
+		for _, r := range top {
+			if d.allows(r.build) {
+				picked = append(picked, r)
+				d.picked = append(d.picked, r.build)
+			}
+		}
+		if err != nil || len(top) < missing {
+			return picked, err
+		}
+	}
+	return picked, nil
+}
 
-	// apply weapon passive
-	if c.weapon.passive != nil {
-		for stat, v := range c.weapon.passive(stats) {
-			stats[stat] = stats[stat] + v
+// searchCandidates returns the n best builds made from the candidates that the diversity allows, sorted for splitting by the bounds.
+// The character of the config must have its fixed bonus merged, see character.withFixedBonus.
+func (c optimizationConfig) searchCandidates(ctx context.Context, progress progressFunc, bounds buildBounds, bySlot candidates, n int, d diversity, buildFilter func(map[artifactSlot]*Artifact) bool) ([]buildResult, error) {
+	bySlot, ok := d.restrict(bySlot)
+	if !ok || bySlot.size() == 0 {
+		return nil, ctx.Err()
+	}
+
+	// every job searches a group of builds, the most promising ones first
+	jobs := bounds.splitInto(bySlot, jobsPerWorker*runtime.GOMAXPROCS(0))
+	jobBounds := make([]float32, len(jobs))
+	for i := range jobs {
+		if jobs[i], ok = d.restrict(jobs[i]); ok {
+			jobBounds[i] = bounds.bound(jobs[i])
 		}
 	}
+	sort.Stable(byBound{jobs, jobBounds})
+
+	// the highest value a job needs to beat to keep a build, so the others can skip the groups below it
+	var shared sharedBest
+
+	// search splits the candidates until a single build is left, skipping the groups whose bound can't beat the kept builds,
+	// and fills the build of the character with every build left. Groups that can only tie with the builds of other jobs
+	// are still searched, so ties are broken the same way on every run.
+	var search func(c optimizationConfig, cands candidates, w splitWeights, top *topBuilds)
+	search = func(c optimizationConfig, cands candidates, w splitWeights, top *topBuilds) {
+		if cancelled(ctx) {
+			return
+		}
+		if cands.size() == 1 {
+			build := c.character.artifacts
+			for slot, arts := range cands {
+				build[artifactSlot(slot)] = arts[0]
+			}
+			if !c.constraints.allowsSets(build) || !d.allows(build) || (buildFilter != nil && !buildFilter(build)) {
+				return
+			}
+			breakdown := c.calculateTargetValue()
+			if !c.constraints.allowsStats(breakdown.stats) {
+				return
+			}
+			if top.add(buildResult{build, breakdown, breakdown.value(c.objective)}) {
+				shared.raise(top.threshold())
+			}
+			return
+		}
 
-	return stats
-}
+		children := bounds.split(cands, &w)
+		childBounds := make([]float32, len(children))
+		for i := range children {
+			// the groups without allowed builds keep a 0 bound, so they are skipped
+			var ok bool
+			if children[i], ok = d.restrict(children[i]); ok {
+				childBounds[i] = bounds.bound(children[i])
+			}
+		}
+		// the most promising group goes first, so more of the others can be skipped
+		sort.Stable(byBound{children, childBounds})
+		for i := range children {
+			if top.wants(childBounds[i]) && childBounds[i] >= shared.get() {
+				search(c, children[i], w, top)
+			}
+		}
+	}
 
-type optimizationConfig struct {
-	character character
-	target    attack
-	artifacts []*Artifact
+	// the best builds made of the leading pieces of every main stat give the jobs a good value to beat from the start
+	c.character.artifacts = make(map[artifactSlot]*Artifact, slotCount)
+	search(c, bySlot.leaders(seedLeaders), splitWeights{}, newTopBuilds(n))
+
+	results := make([]*topBuilds, len(jobs))
+	workers := make([]optimizationConfig, runtime.GOMAXPROCS(0))
+	err := runJobs(ctx, len(jobs), progress, func(worker, index int) {
+		if workers[worker].character.artifacts == nil {
+			workers[worker] = c
+			workers[worker].character.artifacts = make(map[artifactSlot]*Artifact, slotCount)
+		}
+		results[index] = newTopBuilds(n)
+		if jobBounds[index] > 0 && jobBounds[index] >= shared.get() {
+			search(workers[worker], jobs[index], splitWeights{}, results[index])
+		}
+	})
+
+	// the builds of every job are added again from best to worst, the ones of the first jobs winning the ties
+	var all []buildResult
+	for _, top := range results {
+		if top != nil {
+			all = append(all, top.sorted()...)
+		}
+	}
+	sort.SliceStable(all, func(i, j int) bool { return all[i].value > all[j].value })
+	top := newTopBuilds(n)
+	for _, r := range all {
+		top.add(r)
+	}
+	return top.sorted(), err
 }
 
-func (c optimizationConfig) findBest(artifactFilter func([]*Artifact) []*Artifact, buildFilter func(map[artifactSlot]*Artifact) bool) (map[artifactSlot]*Artifact, float32) {
-	artifacts := c.artifacts
+// searchedArtifacts returns the artifacts allowed by the constraints and filter
+func (c optimizationConfig) searchedArtifacts(artifactFilter func([]*Artifact) []*Artifact) []*Artifact {
+	artifacts := c.constraints.filterPieces(c.artifacts)
 	if artifactFilter != nil {
 		artifacts = artifactFilter(artifacts)
 	}
+	return artifacts
+}
+
+// mainStatResult is the best build with a combination of main stats
+type mainStatResult struct {
+	mainStats mainStatRules // a single main stat for the sands, goblet and circlet
+	best      buildResult
+}
+
+// findBestMainStats finds the best build of every combination of sands, goblet and circlet main stats among the searched artifacts,
+// the winning combination first. The main stats of the constraints limit the combinations tried.
+func (c optimizationConfig) findBestMainStats(artifactFilter func([]*Artifact) []*Artifact, buildFilter func(map[artifactSlot]*Artifact) bool) ([]mainStatResult, error) {
+	return c.findBestMainStatsContext(context.Background(), nil, artifactFilter, buildFilter)
+}
 
-	flowers := []*Artifact{}
-	plumes := []*Artifact{}
-	sandss := []*Artifact{}
-	goblets := []*Artifact{}
-	circlets := []*Artifact{}
-
-	for _, art := range artifacts {
-		switch art.Slot {
-		case SlotFlower:
-			flowers = append(flowers, art)
-		case SlotPlume:
-			plumes = append(plumes, art)
-		case SlotSands:
-			sandss = append(sandss, art)
-		case SlotGoblet:
-			goblets = append(goblets, art)
-		case SlotCirclet:
-			circlets = append(circlets, art)
-		}
-	}
-
-	var best map[artifactSlot]*Artifact
-	var bestTargetValue float32
-
-	for _, flower := range flowers {
-		for _, plume := range plumes {
-			for _, sands := range sandss {
-				for _, goblet := range goblets {
-					for _, circlet := range circlets {
-						build := map[artifactSlot]*Artifact{
-							SlotFlower:  flower,
-							SlotPlume:   plume,
-							SlotSands:   sands,
-							SlotGoblet:  goblet,
-							SlotCirclet: circlet,
-						}
-
-						if !buildFilter(build) {
-							continue
-						}
-
-						c.character.artifacts = build
-						value := c.calculateTargetValue()
-						if value > bestTargetValue {
-							best = build
-							bestTargetValue = value
-						}
+// findBestMainStatsContext is findBestMainStats with a parallel search for every combination, see findTopContext.
+// The progress counts the combinations searched. If ctx is done before the search ends,
+// it returns the combinations searched so far and the error of ctx.
+func (c optimizationConfig) findBestMainStatsContext(ctx context.Context, progress progressFunc, artifactFilter func([]*Artifact) []*Artifact, buildFilter func(map[artifactSlot]*Artifact) bool) ([]mainStatResult, error) {
+	searched := c.searchedArtifacts(artifactFilter)
+	if err := c.constraints.validateLocked(searched); err != nil {
+		return nil, err
+	}
+	bySlot := artifactsBySlot(searched)
+	if bySlot.size() == 0 {
+		return nil, ctx.Err()
+	}
+	// the bounds of every combination come from the same pieces, and sorting them groups the pieces of every main stat
+	c.character = c.character.withFixedBonus()
+	bounds := newBuildBounds(c, bySlot)
+	if buildFilter == nil {
+		bySlot = bounds.withoutDominated(bySlot, 1)
+	}
+	bounds.sortForSplitting(bySlot)
+	var runs [slotCount][][]*Artifact
+	for _, slot := range []artifactSlot{SlotSands, SlotGoblet, SlotCirclet} {
+		arts := bySlot[slot]
+		start := 0
+		for i := 1; i <= len(arts); i++ {
+			if i == len(arts) || arts[i].MainStat != arts[start].MainStat {
+				runs[slot] = append(runs[slot], arts[start:i])
+				start = i
+			}
+		}
+	}
+
+	var results []mainStatResult
+	sorted := func() []mainStatResult {
+		sort.SliceStable(results, func(i, j int) bool { return results[i].best.value > results[j].best.value })
+		return results
+	}
+	total := len(runs[SlotSands]) * len(runs[SlotGoblet]) * len(runs[SlotCirclet])
+	done := 0
+	for _, sands := range runs[SlotSands] {
+		for _, goblet := range runs[SlotGoblet] {
+			for _, circlet := range runs[SlotCirclet] {
+				combo := bySlot
+				combo[SlotSands], combo[SlotGoblet], combo[SlotCirclet] = sands, goblet, circlet
+				top, err := c.searchCandidates(ctx, nil, bounds, combo, 1, diversity{}, buildFilter)
+				if err != nil {
+					return sorted(), err
+				}
+				if len(top) > 0 {
+					mainStats := mainStatRules{
+						SlotSands:   {sands[0].MainStat},
+						SlotGoblet:  {goblet[0].MainStat},
+						SlotCirclet: {circlet[0].MainStat},
 					}
+					results = append(results, mainStatResult{mainStats, top[0]})
+				}
+				done++
+				if progress != nil {
+					progress(done, total)
 				}
 			}
 		}
 	}
+	return sorted(), nil
+}
 
-	return best, bestTargetValue
+// findHighestRV returns the build with the highest roll value of the substats, weighted by statRVMultipliers.
+// As there's no character, the stat constraints are checked on the stats the build gives on its own, plus the crit and ER every character has.
+// The filters are called from many goroutines. It returns an error if a locked piece isn't searched, see buildConstraints.validateLocked.
+func findHighestRV(artifacts []*Artifact, statRVMultipliers map[stat]float32, constraints buildConstraints, artifactFilter func([]*Artifact) []*Artifact, buildFilter func(map[artifactSlot]*Artifact) bool) (map[artifactSlot]*Artifact, float32, error) {
+	return findHighestRVContext(context.Background(), nil, artifacts, statRVMultipliers, constraints, artifactFilter, buildFilter)
 }
 
-func findHighestRV(artifacts []*Artifact, statRVMultipliers map[stat]float32, artifactFilter func([]*Artifact) []*Artifact, buildFilter func(map[artifactSlot]*Artifact) bool) (map[artifactSlot]*Artifact, float32) {
+// findHighestRVContext is findHighestRV with a job for every flower and plume pair, run in parallel, see runJobs.
+// If ctx is done before the search ends, it returns the best build found so far and the error of ctx.
+func findHighestRVContext(ctx context.Context, progress progressFunc, artifacts []*Artifact, statRVMultipliers map[stat]float32, constraints buildConstraints, artifactFilter func([]*Artifact) []*Artifact, buildFilter func(map[artifactSlot]*Artifact) bool) (map[artifactSlot]*Artifact, float32, error) {
+	artifacts = constraints.filterPieces(artifacts)
 	if artifactFilter != nil {
 		artifacts = artifactFilter(artifacts)
 	}
+	if err := constraints.validateLocked(artifacts); err != nil {
+		return nil, 0, err
+	}
+
+	bySlot := artifactsBySlot(artifacts)
+	flowers, plumes := bySlot[SlotFlower], bySlot[SlotPlume]
+	sandss, goblets, circlets := bySlot[SlotSands], bySlot[SlotGoblet], bySlot[SlotCirclet]
+
+	type result struct {
+		build map[artifactSlot]*Artifact
+		rv    float32
+	}
+	results := make([]result, len(flowers)*len(plumes))
+
+	// whether the set requirements can be met, by the sets of the flower and plume
+	setsPossible := map[[2]artifactSet]bool{}
+	if len(constraints.sets) > 0 {
+		slotSets := slotSetsOf(bySlot)
+		for _, flower := range flowers {
+			for _, plume := range plumes {
+				pair := [2]artifactSet{flower.Set, plume.Set}
+				if _, ok := setsPossible[pair]; !ok {
+					slotSets[SlotFlower] = map[artifactSet]bool{flower.Set: true}
+					slotSets[SlotPlume] = map[artifactSet]bool{plume.Set: true}
+					setsPossible[pair] = constraints.setsPossibleFrom(slotSets)
+				}
+			}
+		}
+	}
+	checkStats := len(constraints.minStats) > 0 || len(constraints.maxStats) > 0
+	var pieceRVs [slotCount][]float32
+	for slot, arts := range bySlot {
+		for _, art := range arts {
+			pieceRVs[slot] = append(pieceRVs[slot], art.subsQuality(statRVMultipliers))
+		}
+	}
+
+	err := runJobs(ctx, len(results), progress, func(_, index int) {
+		flower, plume := flowers[index/len(plumes)], plumes[index%len(plumes)]
+		res := &results[index]
+		if possible, ok := setsPossible[[2]artifactSet{flower.Set, plume.Set}]; ok && !possible {
+			return
+		}
+		flowerRV, plumeRV := pieceRVs[SlotFlower][index/len(plumes)], pieceRVs[SlotPlume][index%len(plumes)]
+		for s, sands := range sandss {
+			if cancelled(ctx) {
+				return
+			}
+			for g, goblet := range goblets {
+				for c, circlet := range circlets {
+					rv := flowerRV
+					rv += plumeRV
+					rv += pieceRVs[SlotSands][s]
+					rv += pieceRVs[SlotGoblet][g]
+					rv += pieceRVs[SlotCirclet][c]
+					// the constraints are only checked for the builds that would be the best so far
+					if rv <= res.rv {
+						continue
+					}
 
-	flowers := []*Artifact{}
-	plumes := []*Artifact{}
-	sandss := []*Artifact{}
-	goblets := []*Artifact{}
-	circlets := []*Artifact{}
-
-	for _, art := range artifacts {
-		switch art.Slot {
-		case SlotFlower:
-			flowers = append(flowers, art)
-		case SlotPlume:
-			plumes = append(plumes, art)
-		case SlotSands:
-			sandss = append(sandss, art)
-		case SlotGoblet:
-			goblets = append(goblets, art)
-		case SlotCirclet:
-			circlets = append(circlets, art)
-		}
-	}
-
-	var best map[artifactSlot]*Artifact
-	var bestRV float32
-
-	for _, flower := range flowers {
-		for _, plume := range plumes {
-			for _, sands := range sandss {
-				for _, goblet := range goblets {
-					for _, circlet := range circlets {
-						build := map[artifactSlot]*Artifact{
-							SlotFlower:  flower,
-							SlotPlume:   plume,
-							SlotSands:   sands,
-							SlotGoblet:  goblet,
-							SlotCirclet: circlet,
-						}
-
-						if !buildFilter(build) {
-							continue
-						}
-
-						rv := flower.subsQuality(statRVMultipliers)
-						rv += plume.subsQuality(statRVMultipliers)
-						rv += sands.subsQuality(statRVMultipliers)
-						rv += goblet.subsQuality(statRVMultipliers)
-						rv += circlet.subsQuality(statRVMultipliers)
-						if rv > bestRV {
-							best = build
-							bestRV = rv
-						}
+					build := map[artifactSlot]*Artifact{
+						SlotFlower:  flower,
+						SlotPlume:   plume,
+						SlotSands:   sands,
+						SlotGoblet:  goblet,
+						SlotCirclet: circlet,
+					}
+					if !constraints.allowsSets(build) || (buildFilter != nil && !buildFilter(build)) {
+						continue
 					}
+					if checkStats && !constraints.allowsStats(character{artifacts: build}.stats()) {
+						continue
+					}
+					res.build = build
+					res.rv = rv
 				}
 			}
 		}
+	})
+
+	// the first pair wins the ties, like in a sequential search
+	var best result
+	for _, res := range results {
+		if res.rv > best.rv {
+			best = res
+		}
 	}
+	return best.build, best.rv, err
+}
+
+// hitBreakdown explains the damage of one attack of the rotation
+type hitBreakdown struct {
+	attack             attack
+	count              float32
+	baseDamage         float32 // talent multipliers times their stats, plus flat DMG increases
+	critMultiplier     float32
+	dmgBonusMultiplier float32
+	defMultiplier      float32
+	resMultiplier      float32
+	reactionMultiplier float32
+	nonCrit            float32 // of a single hit
+	crit               float32
+	average            float32
+	variance           float32 // of the damage of a single hit, from the chance to crit
+}
 
-	return best, bestRV
+// damageBreakdown explains the damage of a rotation
+type damageBreakdown struct {
+	stats    map[stat]float32 // final stats of the character
+	hits     []hitBreakdown
+	nonCrit  float32 // totals of the rotation, counting every hit
+	crit     float32
+	average  float32
+	variance float32 // of the total, with every hit critting independently
 }
 
-func (c optimizationConfig) calculateTargetValue() float32 {
-	t := c.target
-	stats := c.character.stats()
+// damageObjective is the damage that the optimizer maximizes
+type damageObjective int
 
-	resMult := float32(1.1) // TEMP
-	defMult := float32(0.5) // TEMP
-	mvStatValue := stats[c.target.offensiveStat]
-	critMult := critMultiplier(stats[CritRate], stats[CritDmg])
-	dmgMult := 1 + stats[AnemoDMG]/100 + stats[GlobalDMGBonus]/100 // TEMP
-	return (t.multiplier/100*mvStatValue + stats[BaseDMGIncrease]) * critMult * dmgMult * resMult * defMult
+const (
+	AverageDamage damageObjective = iota // expected damage, for sustained damage
+	CritDamage                           // damage if every hit crits, for one-shot content
+	NonCritDamage
+)
+
+// value returns the total damage of the rotation for the objective
+func (b damageBreakdown) value(objective damageObjective) float32 {
+	switch objective {
+	case CritDamage:
+		return b.crit
+	case NonCritDamage:
+		return b.nonCrit
+	}
+	return b.average
 }
 
+// calculateTargetValue returns the breakdown of the total damage of the rotation
+func (c optimizationConfig) calculateTargetValue() damageBreakdown {
+	return c.breakdownFor(c.character.stats())
+}
+
+// breakdownFor returns the breakdown of the total damage of the rotation with the given final stats
+func (c optimizationConfig) breakdownFor(stats map[stat]float32) damageBreakdown {
+	b := damageBreakdown{stats: stats, hits: make([]hitBreakdown, 0, len(c.rotation))}
+	for _, hit := range c.rotation {
+		h := c.attackDamage(hit.attack, b.stats)
+		h.count = hit.count
+		b.hits = append(b.hits, h)
+		b.nonCrit += h.nonCrit * hit.count
+		b.crit += h.crit * hit.count
+		b.average += h.average * hit.count
+		b.variance += h.variance * hit.count
+	}
+	return b
+}
+
+// target returns the enemy hit by the rotation, standardEnemy if none was configured
+func (c optimizationConfig) target() enemy {
+	if c.enemy.level == 0 {
+		return standardEnemy
+	}
+	return c.enemy
+}
+
+func (c optimizationConfig) attackDamage(t attack, stats map[stat]float32) hitBreakdown {
+	target := c.target()
+	if isTransformative(t.reaction) {
+		// transformative reactions can't crit and ignore DEF and DMG bonuses
+		h := hitBreakdown{
+			attack:             t,
+			baseDamage:         transformativeBaseDamage(t, c.character.level),
+			critMultiplier:     1,
+			dmgBonusMultiplier: 1,
+			defMultiplier:      1,
+			resMultiplier:      target.resMultiplier(transformativeElement(t)),
+			reactionMultiplier: transformativeMultiplier(t, stats),
+		}
+		h.nonCrit = h.baseDamage * h.resMultiplier * h.reactionMultiplier
+		h.crit, h.average = h.nonCrit, h.nonCrit
+		return h
+	}
+
+	mvStatValue := stats[t.offensiveStat]
+	critRate, critDmg := stats[CritRate], stats[CritDmg]
+	dmgBonus := stats[GlobalDMGBonus] + stats[elementDMGBonusStats[t.element]]
+	dmgIncrease := stats[BaseDMGIncrease] + stats[elementDMGIncreaseStats[t.element]] + additiveDMGIncrease(t, c.character.level, stats)
+	if tagged, ok := attackTagStats[t.tag]; ok {
+		critRate += stats[tagged.critRate]
+		critDmg += stats[tagged.critDmg]
+		dmgBonus += stats[tagged.dmgBonus]
+		dmgIncrease += stats[tagged.dmgIncrease]
+	}
+	h := hitBreakdown{
+		attack:             t,
+		baseDamage:         t.multiplier/100*mvStatValue + t.secondaryMultiplier/100*stats[t.secondaryStat] + dmgIncrease,
+		critMultiplier:     critMultiplier(critRate, critDmg),
+		dmgBonusMultiplier: 1 + dmgBonus/100,
+		defMultiplier:      target.defMultiplier(c.character.level),
+		resMultiplier:      target.resMultiplier(t.element),
+		reactionMultiplier: reactionMultiplier(t, stats),
+	}
+	h.nonCrit = h.baseDamage * h.dmgBonusMultiplier * h.defMultiplier * h.resMultiplier * h.reactionMultiplier
+	h.crit = h.nonCrit * (1 + critDmg/100)
+	h.average = h.nonCrit * h.critMultiplier
+	p := clampedCritRate(critRate)
+	h.variance = p * (1 - p) * (h.crit - h.nonCrit) * (h.crit - h.nonCrit)
+	return h
+}
+
+// critMultiplier returns the expected multiplier from crits, with the CRIT Rate clamped between 0% and 100%
 func critMultiplier(critRate, critDmg float32) float32 {
-	finalCR := float32(math.Min(100, float64(critRate))) / 100
-	return finalCR * (1 + critDmg/100)
+	return 1 + clampedCritRate(critRate)*critDmg/100
+}
+
+// clampedCritRate returns the chance to crit, from 0 to 1
+func clampedCritRate(critRate float32) float32 {
+	return float32(math.Max(0, math.Min(100, float64(critRate)))) / 100
 }
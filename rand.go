@@ -3,17 +3,41 @@ package genshinartis
 import (
 	"log"
 	"math/rand"
+	"sort"
 )
 
-func weightedRand(weightedVals map[stat]int) stat {
+// RNG is the source of randomness used to generate artifacts, *rand.Rand implements it
+type RNG interface {
+	Intn(n int) int
+	Float32() float32
+}
+
+// globalRNG uses the top-level functions of math/rand
+type globalRNG struct{}
+
+func (globalRNG) Intn(n int) int {
+	return rand.Intn(n)
+}
+
+func (globalRNG) Float32() float32 {
+	return rand.Float32()
+}
+
+func weightedRand(rng RNG, weightedVals map[stat]int) stat {
+	// map iteration order is random, so values are sorted to get reproducible results from a seeded RNG
+	values := make([]stat, 0, len(weightedVals))
 	sum := 0
-	for _, weight := range weightedVals {
+	for value, weight := range weightedVals {
+		values = append(values, value)
 		sum += weight
 	}
+	sort.Slice(values, func(i, j int) bool {
+		return values[i] < values[j]
+	})
 
-	i := rand.Intn(sum)
-	for value, weight := range weightedVals {
-		i -= weight
+	i := rng.Intn(sum)
+	for _, value := range values {
+		i -= weightedVals[value]
 		if i < 0 {
 			return value
 		}
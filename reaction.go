@@ -0,0 +1,146 @@
+package genshinartis
+
+type reaction int
+
+const (
+	NoReaction reaction = iota
+	// Amplifying reactions, they multiply the damage of the attack that triggers them
+	Vaporize
+	Melt
+	// Transformative reactions, their damage only depends on the character level, EM and reaction bonuses
+	Overloaded
+	Superconduct
+	ElectroCharged
+	Swirl // of the attack's element
+	Shattered
+	Burning
+	Bloom
+	Hyperbloom
+	Burgeon
+	// Additive reactions, they increase the base damage of the attack that triggers them
+	Aggravate
+	Spread
+)
+
+var reactionBonusStats = map[reaction]stat{
+	Vaporize:       VaporizeDMG,
+	Melt:           MeltDMG,
+	Overloaded:     OverloadedDMG,
+	Superconduct:   SuperconductDMG,
+	ElectroCharged: ElectroChargedDMG,
+	Swirl:          SwirlDMG,
+	Shattered:      ShatteredDMG,
+	Burning:        BurningDMG,
+	Bloom:          BloomDMG,
+	Hyperbloom:     HyperbloomDMG,
+	Burgeon:        BurgeonDMG,
+	Aggravate:      AggravateDMG,
+	Spread:         SpreadDMG,
+}
+
+// Base multiplier and element of the transformative reactions, Swirl uses the element of the attack
+var transformativeReactions = map[reaction]struct {
+	multiplier float32
+	element    element
+}{
+	Overloaded:     {2, Pyro},
+	Superconduct:   {0.5, Cryo},
+	ElectroCharged: {1.2, Electro},
+	Swirl:          {0.6, Anemo},
+	Shattered:      {1.5, Physical},
+	Burning:        {0.25, Pyro},
+	Bloom:          {2, Dendro},
+	Hyperbloom:     {3, Dendro},
+	Burgeon:        {3, Dendro},
+}
+
+// Base multiplier and triggering element of the additive reactions
+var additiveReactions = map[reaction]struct {
+	multiplier float32
+	trigger    element
+}{
+	Aggravate: {1.15, Electro},
+	Spread:    {1.25, Dendro},
+}
+
+// Reaction damage by character level, from level 1 to 90
+// From https://genshin-impact.fandom.com/wiki/Level_Scaling
+var reactionLevelMultipliers = [90]float32{
+	17.165606, 18.535048, 19.904854, 21.274902, 22.6454, 24.649612, 26.640642, 28.868587, 31.36768, 34.143345,
+	37.201, 40.66, 44.446667, 48.56352, 53.74848, 59.081898, 64.420044, 69.72446, 75.12314, 80.58478,
+	86.11203, 91.70374, 97.24463, 102.812645, 108.40956, 113.20169, 118.102905, 122.97932, 129.72733, 136.29291,
+	142.67085, 149.02902, 155.41699, 161.8255, 169.10631, 176.51808, 184.07274, 191.70952, 199.55692, 207.38205,
+	215.3989, 224.16566, 233.50217, 243.35057, 256.06308, 268.5435, 281.52606, 295.01364, 309.0672, 323.6016,
+	336.75754, 350.5303, 364.4827, 378.61917, 398.6004, 416.39825, 434.387, 452.95105, 472.60623, 492.8849,
+	513.56854, 539.1032, 565.51056, 592.53876, 624.4434, 651.47015, 679.4968, 707.79407, 736.67145, 765.64026,
+	794.7734, 824.6783, 851.1588, 877.7426, 914.2291, 946.74677, 979.4114, 1011.223, 1044.7912, 1077.4437,
+	1109.9976, 1142.9766, 1176.3695, 1210.1844, 1253.8357, 1288.9528, 1325.4841, 1363.4569, 1405.0974, 1446.8535,
+}
+
+func reactionLevelMultiplier(level int) float32 {
+	if level < 1 {
+		level = 1
+	}
+	if level > len(reactionLevelMultipliers) {
+		level = len(reactionLevelMultipliers)
+	}
+	return reactionLevelMultipliers[level-1]
+}
+
+func isTransformative(r reaction) bool {
+	_, ok := transformativeReactions[r]
+	return ok
+}
+
+// amplifyingMultiplier returns the base multiplier of an amplifying reaction triggered by an attack of the element,
+// or 1 if the element can't trigger it
+func amplifyingMultiplier(r reaction, trigger element) float32 {
+	switch {
+	case r == Vaporize && trigger == Pyro, r == Melt && trigger == Cryo:
+		return 1.5
+	case r == Vaporize && trigger == Hydro, r == Melt && trigger == Pyro:
+		return 2
+	}
+	return 1
+}
+
+// reactionMultiplier returns how much an attack's damage is multiplied by its amplifying reaction
+func reactionMultiplier(t attack, stats map[stat]float32) float32 {
+	base := amplifyingMultiplier(t.reaction, t.element)
+	if base == 1 {
+		return 1
+	}
+	em := stats[ElementalMastery]
+	return base * (1 + 2.78*em/(em+1400) + stats[reactionBonusStats[t.reaction]]/100)
+}
+
+// additiveDMGIncrease returns the base damage an attack gains from its additive reaction,
+// or 0 if its element can't trigger it
+func additiveDMGIncrease(t attack, level int, stats map[stat]float32) float32 {
+	additive, ok := additiveReactions[t.reaction]
+	if !ok || additive.trigger != t.element {
+		return 0
+	}
+	em := stats[ElementalMastery]
+	return additive.multiplier * reactionLevelMultiplier(level) * (1 + 5*em/(em+1200) + stats[reactionBonusStats[t.reaction]]/100)
+}
+
+// transformativeBaseDamage returns the damage of the reaction before EM, reaction bonuses and RES
+func transformativeBaseDamage(t attack, level int) float32 {
+	return transformativeReactions[t.reaction].multiplier * reactionLevelMultiplier(level)
+}
+
+// transformativeMultiplier returns the multiplier from EM and the reaction DMG bonus
+func transformativeMultiplier(t attack, stats map[stat]float32) float32 {
+	em := stats[ElementalMastery]
+	emBonus := 16 * em / (em + 2000)
+	return 1 + emBonus + stats[reactionBonusStats[t.reaction]]/100
+}
+
+// transformativeElement returns the element of the reaction damage, swirls deal the swirled one
+func transformativeElement(t attack) element {
+	if t.reaction == Swirl {
+		return t.element
+	}
+	return transformativeReactions[t.reaction].element
+}
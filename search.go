@@ -0,0 +1,62 @@
+package genshinartis
+
+import (
+	"context"
+	"runtime"
+	"sync"
+)
+
+// progressFunc is told how many of the jobs of a search are done. It's never called concurrently.
+type progressFunc func(done, total int)
+
+// runJobs runs job for every index in [0, jobs) on a pool of GOMAXPROCS workers, starting them in order.
+// job also gets the index of the worker running it, for state that can't be shared between goroutines.
+// Once ctx is done no more jobs are started, and the running ones are expected to return early.
+func runJobs(ctx context.Context, jobs int, progress progressFunc, job func(worker, index int)) error {
+	workers := runtime.GOMAXPROCS(0)
+	if workers > jobs {
+		workers = jobs
+	}
+
+	indexes := make(chan int)
+	var progressMu sync.Mutex
+	done := 0
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for i := range indexes {
+				job(worker, i)
+				if progress != nil {
+					progressMu.Lock()
+					done++
+					progress(done, jobs)
+					progressMu.Unlock()
+				}
+			}
+		}(w)
+	}
+
+feed:
+	for i := 0; i < jobs; i++ {
+		select {
+		case indexes <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(indexes)
+	wg.Wait()
+	return ctx.Err()
+}
+
+// cancelled returns true once ctx is done, without blocking
+func cancelled(ctx context.Context) bool {
+	select {
+	case <-ctx.Done():
+		return true
+	default:
+		return false
+	}
+}
@@ -35,3 +35,49 @@ var AllArtifactSets = []artifactSet{
 	"MarechausseeHunter",
 	"GoldenTroupe",
 }
+
+// Sets that don't drop as 5* artifacts
+// Prayers sets are left out, since they only have circlets
+var FourStarArtifactSets = []artifactSet{
+	"Berserker",
+	"BraveHeart",
+	"DefendersWill",
+	"TheExile",
+	"Gambler",
+	"Instructor",
+	"MartialArtist",
+	"ResolutionOfSojourner",
+	"Scholar",
+	"TinyMiracle",
+}
+
+// Prayers sets, only used when importing
+var circletOnlyArtifactSets = []artifactSet{
+	"PrayersForDestiny",
+	"PrayersForIllumination",
+	"PrayersForWisdom",
+	"PrayersToSpringtime",
+}
+
+// Sets that don't drop as 4* or 5* artifacts
+var ThreeStarArtifactSets = []artifactSet{
+	"Adventurer",
+	"LuckyDog",
+	"TravelingDoctor",
+}
+
+// artifactSetsOfRarity returns every set that can drop with the given rarity
+func artifactSetsOfRarity(rarity int) []artifactSet {
+	// 5* sets drop as 4* too, and 4* sets drop as 3* too
+	sets := []artifactSet{}
+	if rarity >= 4 {
+		sets = append(sets, AllArtifactSets...)
+	}
+	if rarity >= 3 && rarity <= 4 {
+		sets = append(sets, FourStarArtifactSets...)
+	}
+	if rarity <= 3 {
+		sets = append(sets, ThreeStarArtifactSets...)
+	}
+	return sets
+}
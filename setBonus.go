@@ -1,46 +1,330 @@
 package genshinartis
 
-func artifactSetBonus(artifactBuild map[artifactSlot]*Artifact) map[stat]float32 {
-	bonus := map[stat]float32{}
+// setEffect is what a set grants once enough of its pieces are equipped
+type setEffect struct {
+	stats       map[stat]float32                  // always active
+	conditional func(stacks int) map[stat]float32 // only active under some condition, see condition
+	maxStacks   int                               // of the conditional effect, 0 if it doesn't stack
+	conversion  conversion                        // calculated from the stats before conversions
+}
+
+// condition configures the conditional effect of a set or weapon.
+// The zero value is an inactive condition, use alwaysActive or activeFor for an active one.
+type condition struct {
+	stacks int     // capped at the max stacks of the effect
+	uptime float32 // fraction of the time the effect is active, clamped to [0, 1]
+}
+
+// alwaysActive is the condition assumed for sets without a configured one, with the max amount of stacks
+func alwaysActive(stacks int) condition {
+	return condition{stacks: stacks, uptime: 1}
+}
+
+// activeFor is an active condition that only lasts the given fraction of the time
+func activeFor(stacks int, uptime float32) condition {
+	return condition{stacks: stacks, uptime: uptime}
+}
+
+// active returns true if the effect is active for some of the time
+func (c condition) active() bool {
+	return c.uptime > 0
+}
+
+// cappedStacks returns the stacks of the condition, limited to the range of an effect
+func (c condition) cappedStacks(maxStacks int) int {
+	if c.stacks > maxStacks {
+		return maxStacks
+	}
+	if c.stacks < 0 {
+		return 0
+	}
+	return c.stacks
+}
+
+// activeUptime returns the fraction of the time the effect is active, the uptime clamped to [0, 1]
+func (c condition) activeUptime() float32 {
+	switch {
+	case c.uptime > 1:
+		return 1
+	case c.uptime < 0:
+		return 0
+	}
+	return c.uptime
+}
+
+// stacking returns a conditional effect that grants the base stats, plus the perStack stats for every stack
+func stacking(base, perStack map[stat]float32) func(int) map[stat]float32 {
+	return func(stacks int) map[stat]float32 {
+		result := map[stat]float32{}
+		for stat, value := range base {
+			result[stat] = result[stat] + value
+		}
+		for stat, value := range perStack {
+			result[stat] = result[stat] + value*float32(stacks)
+		}
+		return result
+	}
+}
+
+// whileActive returns a conditional effect without stacks
+func whileActive(stats map[stat]float32) func(int) map[stat]float32 {
+	return stacking(stats, nil)
+}
+
+type setBonus struct {
+	twoPiece  setEffect
+	fourPiece setEffect
+}
+
+// Effects that don't change the wearer's stats (shields, healing received, enemy RES, etc) are not modeled
+// From https://genshin-impact.fandom.com/wiki/Artifact/Sets
+var setBonuses = map[artifactSet]setBonus{
+	"GladiatorsFinale": {
+		twoPiece:  setEffect{stats: map[stat]float32{ATKP: 18}},
+		fourPiece: setEffect{conditional: whileActive(map[stat]float32{NormalAttackDMG: 35})}, // sword, claymore or polearm users
+	},
+	"WanderersTroupe": {
+		twoPiece:  setEffect{stats: map[stat]float32{ElementalMastery: 80}},
+		fourPiece: setEffect{conditional: whileActive(map[stat]float32{ChargedAttackDMG: 35})}, // catalyst or bow users
+	},
+	"Thundersoother": {
+		fourPiece: setEffect{conditional: whileActive(map[stat]float32{GlobalDMGBonus: 35})}, // against enemies affected by Electro
+	},
+	"ThunderingFury": {
+		twoPiece: setEffect{stats: map[stat]float32{ElectroDMG: 15}},
+		fourPiece: setEffect{stats: map[stat]float32{
+			OverloadedDMG: 40, ElectroChargedDMG: 40, SuperconductDMG: 40, HyperbloomDMG: 40, AggravateDMG: 20,
+		}},
+	},
+	"MaidenBeloved": {
+		twoPiece: setEffect{stats: map[stat]float32{HealingBonus: 15}},
+	},
+	"ViridescentVenerer": {
+		twoPiece:  setEffect{stats: map[stat]float32{AnemoDMG: 15}},
+		fourPiece: setEffect{stats: map[stat]float32{SwirlDMG: 60}},
+	},
+	"CrimsonWitchOfFlames": {
+		twoPiece: setEffect{stats: map[stat]float32{PyroDMG: 15}},
+		fourPiece: setEffect{
+			stats:       map[stat]float32{VaporizeDMG: 15, MeltDMG: 15, OverloadedDMG: 40, BurningDMG: 40, BurgeonDMG: 40},
+			conditional: stacking(nil, map[stat]float32{PyroDMG: 7.5}), maxStacks: 3, // stacks after using a skill
+		},
+	},
+	"Lavawalker": {
+		fourPiece: setEffect{conditional: whileActive(map[stat]float32{GlobalDMGBonus: 35})}, // against enemies affected by Pyro
+	},
+	"NoblesseOblige": {
+		twoPiece:  setEffect{stats: map[stat]float32{BurstDMG: 20}},
+		fourPiece: setEffect{conditional: whileActive(map[stat]float32{ATKP: 20})}, // after using a burst
+	},
+	"BloodstainedChivalry": {
+		twoPiece:  setEffect{stats: map[stat]float32{PhysDMG: 25}},
+		fourPiece: setEffect{conditional: whileActive(map[stat]float32{ChargedAttackDMG: 50})}, // after defeating an enemy
+	},
+	"ArchaicPetra": {
+		twoPiece: setEffect{stats: map[stat]float32{GeoDMG: 15}},
+	},
+	"RetracingBolide": {
+		fourPiece: setEffect{conditional: whileActive(map[stat]float32{NormalAttackDMG: 40, ChargedAttackDMG: 40})}, // while shielded
+	},
+	"BlizzardStrayer": {
+		twoPiece:  setEffect{stats: map[stat]float32{CryoDMG: 15}},
+		fourPiece: setEffect{conditional: stacking(map[stat]float32{CritRate: 20}, map[stat]float32{CritRate: 20}), maxStacks: 1}, // against cryo enemies, 1 stack if frozen
+	},
+	"HeartOfDepth": {
+		twoPiece:  setEffect{stats: map[stat]float32{HydroDMG: 15}},
+		fourPiece: setEffect{conditional: whileActive(map[stat]float32{NormalAttackDMG: 30, ChargedAttackDMG: 30})}, // after using a skill
+	},
+	"TenacityOfTheMillelith": {
+		twoPiece:  setEffect{stats: map[stat]float32{HPP: 20}},
+		fourPiece: setEffect{conditional: whileActive(map[stat]float32{ATKP: 20})}, // after a skill hits
+	},
+	"PaleFlame": {
+		twoPiece: setEffect{stats: map[stat]float32{PhysDMG: 25}},
+		fourPiece: setEffect{conditional: func(stacks int) map[stat]float32 {
+			if stacks < 2 {
+				return map[stat]float32{ATKP: 9 * float32(stacks)}
+			}
+			return map[stat]float32{ATKP: 9 * 2, PhysDMG: 25}
+		}, maxStacks: 2}, // stacks when a skill hits
+	},
+	"EmblemOfSeveredFate": {
+		twoPiece: setEffect{stats: map[stat]float32{EnergyRecharge: 20}},
+		fourPiece: setEffect{conversion: func(s map[stat]float32) map[stat]float32 {
+			return map[stat]float32{BurstDMG: minf(75, s[EnergyRecharge]*0.25)}
+		}},
+	},
+	"ShimenawasReminiscence": {
+		twoPiece:  setEffect{stats: map[stat]float32{ATKP: 18}},
+		fourPiece: setEffect{conditional: whileActive(map[stat]float32{NormalAttackDMG: 50, ChargedAttackDMG: 50, PlungeDMG: 50})}, // after using a skill
+	},
+	"HuskOfOpulentDreams": {
+		twoPiece:  setEffect{stats: map[stat]float32{DEFP: 30}},
+		fourPiece: setEffect{conditional: stacking(nil, map[stat]float32{DEFP: 6, GeoDMG: 6}), maxStacks: 4}, // stacks while on-field or when hit by geo attacks
+	},
+	"OceanHuedClam": {
+		twoPiece: setEffect{stats: map[stat]float32{HealingBonus: 15}},
+	},
+	"EchoesOfAnOffering": {
+		twoPiece: setEffect{stats: map[stat]float32{ATKP: 18}},
+	},
+	"VermillionHereafter": {
+		twoPiece:  setEffect{stats: map[stat]float32{ATKP: 18}},
+		fourPiece: setEffect{conditional: stacking(map[stat]float32{ATKP: 8}, map[stat]float32{ATKP: 10}), maxStacks: 4}, // after using a burst, stacks when losing HP
+	},
+	"DeepwoodMemories": {
+		twoPiece: setEffect{stats: map[stat]float32{DendroDMG: 15}},
+	},
+	"GildedDreams": {
+		twoPiece: setEffect{stats: map[stat]float32{ElementalMastery: 80}},
+		// after a reaction, stacks are the party members of a different element, the rest share the wearer's element
+		fourPiece: setEffect{conditional: func(stacks int) map[stat]float32 {
+			return map[stat]float32{ElementalMastery: 50 * float32(stacks), ATKP: 14 * float32(3-stacks)}
+		}, maxStacks: 3},
+	},
+	"DesertPavilionChronicle": {
+		twoPiece:  setEffect{stats: map[stat]float32{AnemoDMG: 15}},
+		fourPiece: setEffect{conditional: whileActive(map[stat]float32{NormalAttackDMG: 40, ChargedAttackDMG: 40, PlungeDMG: 40})}, // after a charged attack hits
+	},
+	"FlowerOfParadiseLost": {
+		twoPiece: setEffect{stats: map[stat]float32{ElementalMastery: 80}},
+		fourPiece: setEffect{
+			stats:       map[stat]float32{BloomDMG: 40, HyperbloomDMG: 40, BurgeonDMG: 40},
+			conditional: stacking(nil, map[stat]float32{BloomDMG: 10, HyperbloomDMG: 10, BurgeonDMG: 10}), maxStacks: 4, // stacks after triggering them
+		},
+	},
+	"NymphsDream": {
+		twoPiece: setEffect{stats: map[stat]float32{HydroDMG: 15}},
+		fourPiece: setEffect{conditional: func(stacks int) map[stat]float32 {
+			atk := []float32{0, 7, 16, 25}
+			hydro := []float32{0, 4, 9, 15}
+			return map[stat]float32{ATKP: atk[stacks], HydroDMG: hydro[stacks]}
+		}, maxStacks: 3}, // stacks when hitting enemies
+	},
+	"VourukashasGlow": {
+		twoPiece:  setEffect{stats: map[stat]float32{HPP: 20}},
+		fourPiece: setEffect{stats: map[stat]float32{SkillDMG: 10, BurstDMG: 10}, conditional: stacking(nil, map[stat]float32{SkillDMG: 8, BurstDMG: 8}), maxStacks: 5}, // stacks when losing HP
+	},
+	"MarechausseeHunter": {
+		twoPiece:  setEffect{stats: map[stat]float32{NormalAttackDMG: 15, ChargedAttackDMG: 15}},
+		fourPiece: setEffect{conditional: stacking(nil, map[stat]float32{CritRate: 12}), maxStacks: 3}, // stacks when HP changes
+	},
+	"GoldenTroupe": {
+		twoPiece:  setEffect{stats: map[stat]float32{SkillDMG: 20}},
+		fourPiece: setEffect{stats: map[stat]float32{SkillDMG: 25}, conditional: whileActive(map[stat]float32{SkillDMG: 25})}, // while off-field
+	},
+
+	// 4* and 3* sets
+	"Berserker": {
+		twoPiece:  setEffect{stats: map[stat]float32{CritRate: 12}},
+		fourPiece: setEffect{conditional: whileActive(map[stat]float32{CritRate: 24})}, // below 70% HP
+	},
+	"BraveHeart": {
+		twoPiece:  setEffect{stats: map[stat]float32{ATKP: 18}},
+		fourPiece: setEffect{conditional: whileActive(map[stat]float32{GlobalDMGBonus: 30})}, // against enemies above 50% HP
+	},
+	"DefendersWill": {
+		twoPiece: setEffect{stats: map[stat]float32{DEFP: 30}},
+	},
+	"TheExile": {
+		twoPiece: setEffect{stats: map[stat]float32{EnergyRecharge: 20}},
+	},
+	"Gambler": {
+		twoPiece: setEffect{stats: map[stat]float32{SkillDMG: 20}},
+	},
+	"Instructor": {
+		twoPiece:  setEffect{stats: map[stat]float32{ElementalMastery: 80}},
+		fourPiece: setEffect{conditional: whileActive(map[stat]float32{ElementalMastery: 120})}, // after triggering a reaction
+	},
+	"MartialArtist": {
+		twoPiece:  setEffect{stats: map[stat]float32{NormalAttackDMG: 15, ChargedAttackDMG: 15}},
+		fourPiece: setEffect{conditional: whileActive(map[stat]float32{NormalAttackDMG: 25, ChargedAttackDMG: 25})}, // after using a skill
+	},
+	"ResolutionOfSojourner": {
+		twoPiece:  setEffect{stats: map[stat]float32{ATKP: 18}},
+		fourPiece: setEffect{stats: map[stat]float32{ChargedAttackCritRate: 30}},
+	},
+	"Scholar": {
+		twoPiece: setEffect{stats: map[stat]float32{EnergyRecharge: 20}},
+	},
+	"TinyMiracle": {}, // only elemental RES
+	"Adventurer": {
+		twoPiece: setEffect{stats: map[stat]float32{HP: 1000}},
+	},
+	"LuckyDog": {
+		twoPiece: setEffect{stats: map[stat]float32{DEF: 100}},
+	},
+	"TravelingDoctor": {}, // only healing
+}
+
+// activeSetEffects returns the effects of every set with enough pieces in the build, along with the set they belong to
+func activeSetEffects(artifactBuild map[artifactSlot]*Artifact) map[artifactSet][]setEffect {
+	effects := map[artifactSet][]setEffect{}
 	setCount := map[artifactSet]int{}
 	for _, artifact := range artifactBuild {
 		setCount[artifact.Set] = setCount[artifact.Set] + 1
 	}
 	for set, count := range setCount {
 		if count >= 2 {
-			set2pBonus := twoPieceBonus(set)
-			for stat, value := range set2pBonus {
-				bonus[stat] = bonus[stat] + value
-			}
+			effects[set] = append(effects[set], setBonuses[set].twoPiece)
 		}
 		if count >= 4 {
-			set4pBonus := fourPieceBonus(set)
-			for stat, value := range set4pBonus {
+			effects[set] = append(effects[set], setBonuses[set].fourPiece)
+		}
+	}
+	return effects
+}
+
+// artifactSetBonus returns the stats granted by the sets of the build, except the ones from set conversions.
+// Conditional effects use the condition configured for their set, or are assumed to be always active at max stacks.
+func artifactSetBonus(artifactBuild map[artifactSlot]*Artifact, conditions map[artifactSet]condition) map[stat]float32 {
+	bonus := map[stat]float32{}
+	for set, effects := range activeSetEffects(artifactBuild) {
+		for _, effect := range effects {
+			for stat, value := range effect.stats {
 				bonus[stat] = bonus[stat] + value
 			}
+			if effect.conditional == nil {
+				continue
+			}
+			cond, ok := conditions[set]
+			if !ok {
+				cond = alwaysActive(effect.maxStacks)
+			}
+			if !cond.active() {
+				continue
+			}
+			for stat, value := range effect.conditional(cond.cappedStacks(effect.maxStacks)) {
+				bonus[stat] = bonus[stat] + value*cond.activeUptime()
+			}
 		}
 	}
 	return bonus
 }
 
-// Very WIP, no stacks config, not all sets, etc
+// artifactSetConversions returns the set effects that are calculated from other stats of the character
+func artifactSetConversions(artifactBuild map[artifactSlot]*Artifact) []conversion {
+	conversions := []conversion{}
+	for _, effects := range activeSetEffects(artifactBuild) {
+		for _, effect := range effects {
+			if effect.conversion != nil {
+				conversions = append(conversions, effect.conversion)
+			}
+		}
+	}
+	return conversions
+}
 
-func twoPieceBonus(set artifactSet) map[stat]float32 {
-	bonus := map[stat]float32{}
-	switch set {
-	case "VermillionHereafter":
-		bonus[ATKP] = 18
+func minf(a, b float32) float32 {
+	if a < b {
+		return a
 	}
-	return bonus
+	return b
 }
 
-func fourPieceBonus(set artifactSet) map[stat]float32 {
-	bonus := map[stat]float32{}
-	switch set {
-	case "VermillionHereafter":
-		bonus[ATKP] = 8 + 10*4
-	case "MarechausseeHunter":
-		bonus[CritRate] = 12 * 3
+func maxf(a, b float32) float32 {
+	if a > b {
+		return a
 	}
-	return bonus
+	return b
 }
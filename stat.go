@@ -1,9 +1,5 @@
 package genshinartis
 
-import (
-	"math/rand"
-)
-
 type stat int
 
 const (
@@ -28,41 +24,117 @@ const (
 	HealingBonus
 
 	GlobalDMGBonus
+	NormalAttackDMG
+	ChargedAttackDMG
+	PlungeDMG
+	SkillDMG
+	BurstDMG
+	NormalAttackCritRate
+	ChargedAttackCritRate
+	PlungeCritRate
+	SkillCritRate
+	BurstCritRate
+	NormalAttackCritDmg
+	ChargedAttackCritDmg
+	PlungeCritDmg
+	SkillCritDmg
+	BurstCritDmg
 	BaseDMGIncrease
+	NormalAttackDMGIncrease
+	ChargedAttackDMGIncrease
+	PlungeDMGIncrease
+	SkillDMGIncrease
+	BurstDMGIncrease
+	PhysDMGIncrease
+	PyroDMGIncrease
+	HydroDMGIncrease
+	AnemoDMGIncrease
+	ElectroDMGIncrease
+	DendroDMGIncrease
+	CryoDMGIncrease
+	GeoDMGIncrease
+	VaporizeDMG
+	MeltDMG
+	OverloadedDMG
+	SuperconductDMG
+	ElectroChargedDMG
+	SwirlDMG
+	ShatteredDMG
+	BurningDMG
+	BloomDMG
+	HyperbloomDMG
+	BurgeonDMG
+	AggravateDMG
+	SpreadDMG
+	BaseHP  // HP before any bonus, only known once the stats are calculated
+	BaseATK // character plus weapon base ATK
+	BaseDEF
 )
 
-var substatValues map[stat][4]float32 = map[stat][4]float32{
-	HP:               {209.13, 239.00, 268.88, 298.75},
-	ATK:              {13.62, 15.56, 17.51, 19.45},
-	DEF:              {16.20, 18.52, 20.83, 23.15},
-	HPP:              {4.08, 4.66, 5.25, 5.83},
-	ATKP:             {4.08, 4.66, 5.25, 5.83},
-	DEFP:             {5.10, 5.83, 6.56, 7.29},
-	ElementalMastery: {16.32, 18.65, 20.98, 23.31},
-	EnergyRecharge:   {4.53, 5.18, 5.83, 6.48},
-	CritRate:         {2.72, 3.11, 3.50, 3.89},
-	CritDmg:          {5.44, 6.22, 6.99, 7.77},
-}
-
-var mainStatValues map[stat]float32 = map[stat]float32{
-	HP:               4780,
-	ATK:              311,
-	HPP:              46.6,
-	ATKP:             46.6,
-	DEFP:             58.3,
-	ElementalMastery: 186.5,
-	EnergyRecharge:   51.8,
-	PyroDMG:          46.6,
-	ElectroDMG:       46.6,
-	CryoDMG:          46.6,
-	HydroDMG:         46.6,
-	AnemoDMG:         46.6,
-	GeoDMG:           46.6,
-	DendroDMG:        46.6,
-	PhysDMG:          58.3,
-	CritRate:         31.1,
-	CritDmg:          62.2,
-	HealingBonus:     35.9,
+// Possible values of a single substat roll, by rarity.
+// 1* and 2* artifacts have fewer roll tiers, so some of their values are repeated. They aren't generated,
+// only their lowest and highest rolls are used.
+var substatValues map[int]map[stat][4]float32 = map[int]map[stat][4]float32{
+	1: {
+		HP:               {23.90, 23.90, 29.88, 29.88},
+		ATK:              {1.56, 1.56, 1.95, 1.95},
+		DEF:              {1.85, 1.85, 2.31, 2.31},
+		HPP:              {1.17, 1.17, 1.46, 1.46},
+		ATKP:             {1.17, 1.17, 1.46, 1.46},
+		DEFP:             {1.46, 1.46, 1.82, 1.82},
+		ElementalMastery: {4.66, 4.66, 5.83, 5.83},
+		EnergyRecharge:   {1.30, 1.30, 1.62, 1.62},
+		CritRate:         {0.78, 0.78, 0.97, 0.97},
+		CritDmg:          {1.55, 1.55, 1.94, 1.94},
+	},
+	2: {
+		HP:               {50.19, 60.95, 60.95, 71.70},
+		ATK:              {3.27, 3.97, 3.97, 4.67},
+		DEF:              {3.89, 4.72, 4.72, 5.56},
+		HPP:              {1.63, 1.98, 1.98, 2.33},
+		ATKP:             {1.63, 1.98, 1.98, 2.33},
+		DEFP:             {2.04, 2.48, 2.48, 2.91},
+		ElementalMastery: {6.53, 7.93, 7.93, 9.33},
+		EnergyRecharge:   {1.81, 2.20, 2.20, 2.59},
+		CritRate:         {1.09, 1.32, 1.32, 1.55},
+		CritDmg:          {2.18, 2.64, 2.64, 3.11},
+	},
+	3: {
+		HP:               {100.38, 114.72, 129.06, 143.40},
+		ATK:              {6.54, 7.47, 8.40, 9.34},
+		DEF:              {7.78, 8.89, 10.00, 11.11},
+		HPP:              {2.45, 2.80, 3.15, 3.50},
+		ATKP:             {2.45, 2.80, 3.15, 3.50},
+		DEFP:             {3.06, 3.50, 3.93, 4.37},
+		ElementalMastery: {9.79, 11.19, 12.59, 13.99},
+		EnergyRecharge:   {2.72, 3.11, 3.50, 3.89},
+		CritRate:         {1.63, 1.86, 2.10, 2.33},
+		CritDmg:          {3.26, 3.73, 4.20, 4.66},
+	},
+	4: {
+		HP:               {167.30, 191.20, 215.10, 239.00},
+		ATK:              {10.89, 12.45, 14.00, 15.56},
+		DEF:              {12.96, 14.82, 16.67, 18.52},
+		HPP:              {3.26, 3.73, 4.20, 4.66},
+		ATKP:             {3.26, 3.73, 4.20, 4.66},
+		DEFP:             {4.08, 4.66, 5.25, 5.83},
+		ElementalMastery: {13.06, 14.92, 16.79, 18.65},
+		EnergyRecharge:   {3.63, 4.14, 4.66, 5.18},
+		CritRate:         {2.18, 2.49, 2.80, 3.11},
+		CritDmg:          {4.35, 4.97, 5.60, 6.22},
+	},
+	5: {
+		HP:               {209.13, 239.00, 268.88, 298.75},
+		ATK:              {13.62, 15.56, 17.51, 19.45},
+		DEF:              {16.20, 18.52, 20.83, 23.15},
+		HPP:              {4.08, 4.66, 5.25, 5.83},
+		ATKP:             {4.08, 4.66, 5.25, 5.83},
+		DEFP:             {5.10, 5.83, 6.56, 7.29},
+		ElementalMastery: {16.32, 18.65, 20.98, 23.31},
+		EnergyRecharge:   {4.53, 5.18, 5.83, 6.48},
+		CritRate:         {2.72, 3.11, 3.50, 3.89},
+		CritDmg:          {5.44, 6.22, 6.99, 7.77},
+	},
 }
 
 func (s stat) String() string {
@@ -105,12 +177,114 @@ func (s stat) String() string {
 		return "Physical DMG%"
 	case HealingBonus:
 		return "Healing Bonus%"
+	case GlobalDMGBonus:
+		return "DMG%"
+	case NormalAttackDMG:
+		return "Normal Attack DMG%"
+	case ChargedAttackDMG:
+		return "Charged Attack DMG%"
+	case PlungeDMG:
+		return "Plunging Attack DMG%"
+	case SkillDMG:
+		return "Elemental Skill DMG%"
+	case BurstDMG:
+		return "Elemental Burst DMG%"
+	case NormalAttackCritRate:
+		return "Normal Attack CRIT Rate%"
+	case ChargedAttackCritRate:
+		return "Charged Attack CRIT Rate%"
+	case PlungeCritRate:
+		return "Plunging Attack CRIT Rate%"
+	case SkillCritRate:
+		return "Elemental Skill CRIT Rate%"
+	case BurstCritRate:
+		return "Elemental Burst CRIT Rate%"
+	case NormalAttackCritDmg:
+		return "Normal Attack CRIT DMG%"
+	case ChargedAttackCritDmg:
+		return "Charged Attack CRIT DMG%"
+	case PlungeCritDmg:
+		return "Plunging Attack CRIT DMG%"
+	case SkillCritDmg:
+		return "Elemental Skill CRIT DMG%"
+	case BurstCritDmg:
+		return "Elemental Burst CRIT DMG%"
+	case BaseDMGIncrease:
+		return "Base DMG Increase"
+	case NormalAttackDMGIncrease:
+		return "Normal Attack DMG Increase"
+	case ChargedAttackDMGIncrease:
+		return "Charged Attack DMG Increase"
+	case PlungeDMGIncrease:
+		return "Plunging Attack DMG Increase"
+	case SkillDMGIncrease:
+		return "Elemental Skill DMG Increase"
+	case BurstDMGIncrease:
+		return "Elemental Burst DMG Increase"
+	case PhysDMGIncrease:
+		return "Physical DMG Increase"
+	case PyroDMGIncrease:
+		return "Pyro DMG Increase"
+	case HydroDMGIncrease:
+		return "Hydro DMG Increase"
+	case AnemoDMGIncrease:
+		return "Anemo DMG Increase"
+	case ElectroDMGIncrease:
+		return "Electro DMG Increase"
+	case DendroDMGIncrease:
+		return "Dendro DMG Increase"
+	case CryoDMGIncrease:
+		return "Cryo DMG Increase"
+	case GeoDMGIncrease:
+		return "Geo DMG Increase"
+	case VaporizeDMG:
+		return "Vaporize DMG%"
+	case MeltDMG:
+		return "Melt DMG%"
+	case OverloadedDMG:
+		return "Overloaded DMG%"
+	case SuperconductDMG:
+		return "Superconduct DMG%"
+	case ElectroChargedDMG:
+		return "Electro-Charged DMG%"
+	case SwirlDMG:
+		return "Swirl DMG%"
+	case ShatteredDMG:
+		return "Shattered DMG%"
+	case BurningDMG:
+		return "Burning DMG%"
+	case BloomDMG:
+		return "Bloom DMG%"
+	case HyperbloomDMG:
+		return "Hyperbloom DMG%"
+	case BurgeonDMG:
+		return "Burgeon DMG%"
+	case AggravateDMG:
+		return "Aggravate DMG%"
+	case SpreadDMG:
+		return "Spread DMG%"
+	case BaseHP:
+		return "Base HP"
+	case BaseATK:
+		return "Base ATK"
+	case BaseDEF:
+		return "Base DEF"
 	}
 	return "Unknown"
 }
 
+// RandomRollValue returns the value of a random roll on a MaxRarity artifact
 func (s stat) RandomRollValue() float32 {
-	return substatValues[s][rand.Intn(4)]
+	return s.RandomRollValueWithRarity(MaxRarity)
+}
+
+// RandomRollValueWithRarity works like RandomRollValue for the given rarity, panicking on unsupported ones
+func (s stat) RandomRollValueWithRarity(rarity int) float32 {
+	return s.randomRollValue(globalRNG{}, generatedRarity(rarity))
+}
+
+func (s stat) randomRollValue(rng RNG, rarity int) float32 {
+	return substatValues[rarity][s][rng.Intn(4)]
 }
 
 // Weights from https://genshin-impact.fandom.com/wiki/Artifacts/Distribution
@@ -149,6 +323,25 @@ var circletWeightedStats = map[stat]int{
 	ElementalMastery: 4_000,
 }
 
+func isValidMainStat(slot artifactSlot, s stat) bool {
+	switch slot {
+	case SlotFlower:
+		return s == HP
+	case SlotPlume:
+		return s == ATK
+	case SlotSands:
+		_, ok := sandsWeightedStats[s]
+		return ok
+	case SlotGoblet:
+		_, ok := gobletWeightedStats[s]
+		return ok
+	case SlotCirclet:
+		_, ok := circletWeightedStats[s]
+		return ok
+	}
+	return false
+}
+
 const (
 	flatSubstatWeight   = 150
 	commonSubstatWeight = 100
@@ -0,0 +1,140 @@
+package genshinartis
+
+// conversion calculates stats from other stats of the character, like Homa's HP to ATK.
+// It only sees the stats before any conversion, so conversions can't feed themselves or each other.
+type conversion func(map[stat]float32) map[stat]float32
+
+// statPipeline holds the stats of a character after every stage of their calculation, in order
+type statPipeline struct {
+	base        map[stat]float32 // base HP, ATK and DEF of the character and weapon, and the crit and ER every character has
+	percent     map[stat]float32 // plus every non flat stat, and HP, ATK and DEF with their percent bonuses applied
+	flat        map[stat]float32 // plus flat HP, ATK and DEF
+	conversions map[stat]float32 // only the stats given by conversions, calculated from the flat stage
+	final       map[stat]float32 // the flat stage plus the conversions
+}
+
+// flatStats are added in the flat stage, after their percent counterparts are applied to the base stats
+var flatStats = map[stat]stat{HP: HPP, ATK: ATKP, DEF: DEFP}
+
+func copyStats(s map[stat]float32) map[stat]float32 {
+	c := make(map[stat]float32, len(s))
+	for stat, v := range s {
+		c[stat] = v
+	}
+	return c
+}
+
+// allBonusStats returns every stat from the character, weapon, artifacts, sets and team buffs
+func (c character) allBonusStats(artifactStats, setBonus map[stat]float32) map[stat]float32 {
+	bonus := make(map[stat]float32, len(c.bonusStats)+len(c.weapon.stats)+len(artifactStats)+len(setBonus)+len(flatStats))
+	add := func(stats map[stat]float32) {
+		for stat, v := range stats {
+			bonus[stat] = bonus[stat] + v
+		}
+	}
+	add(c.bonusStats)
+	add(c.weapon.stats)
+	add(artifactStats)
+	add(setBonus)
+	for _, provider := range c.buffs {
+		add(provider.buff())
+	}
+	return bonus
+}
+
+// allConversions returns the conversions of the sets, weapon and character
+func (c character) allConversions(setConversions []conversion) []conversion {
+	conversions := make([]conversion, 0, len(setConversions)+1+len(c.conversions))
+	conversions = append(conversions, setConversions...)
+	if c.weapon.conversion != nil {
+		conversions = append(conversions, c.weapon.conversion)
+	}
+	return append(conversions, c.conversions...)
+}
+
+// withFixedBonus returns the character with every stat that doesn't depend on the artifacts merged into its bonus stats,
+// so they aren't calculated again for every build
+func (c character) withFixedBonus() character {
+	c.bonusStats = c.allBonusStats(nil, nil)
+	c.weapon.stats = nil
+	c.buffs = nil
+	return c
+}
+
+// finalStatsWith returns the final stage of statStagesWith without keeping the other ones, as the optimizer calculates it for every build
+func (c character) finalStatsWith(artifactStats, setBonus map[stat]float32, setConversions []conversion) map[stat]float32 {
+	return c.finalStatsOf(c.allBonusStats(artifactStats, setBonus), setConversions)
+}
+
+// finalStatsOf is finalStatsWith from every bonus stat of the character, see allBonusStats. It adds the final stats to them.
+func (c character) finalStatsOf(s map[stat]float32, setConversions []conversion) map[stat]float32 {
+	s[HP] = c.baseHP*(1+s[HPP]/100) + s[HP]
+	s[ATK] = c.baseATK()*(1+s[ATKP]/100) + s[ATK]
+	s[DEF] = c.baseDef*(1+s[DEFP]/100) + s[DEF]
+	s[BaseHP], s[BaseATK], s[BaseDEF] = c.baseHP, c.baseATK(), c.baseDef
+	s[CritRate] = 5 + s[CritRate]
+	s[CritDmg] = 50 + s[CritDmg]
+	s[EnergyRecharge] = 100 + s[EnergyRecharge]
+
+	converted := map[stat]float32{}
+	for _, convert := range c.allConversions(setConversions) {
+		for stat, v := range convert(s) {
+			converted[stat] = converted[stat] + v
+		}
+	}
+	for stat, v := range converted {
+		s[stat] = s[stat] + v
+	}
+	return s
+}
+
+// statStages returns the stats of the character after every stage of their calculation
+func (c character) statStages() statPipeline {
+	return c.statStagesWith(c.artifactStats(), artifactSetBonus(c.artifacts, c.setConditions), artifactSetConversions(c.artifacts))
+}
+
+// statStagesWith returns the stats of the character with the given artifact stats and set effects instead of the equipped ones
+func (c character) statStagesWith(artifactStats, setBonus map[stat]float32, setConversions []conversion) statPipeline {
+	var p statPipeline
+	bonus := c.allBonusStats(artifactStats, setBonus)
+
+	p.base = map[stat]float32{
+		BaseHP:         c.baseHP,
+		BaseATK:        c.baseATK(),
+		BaseDEF:        c.baseDef,
+		HP:             c.baseHP,
+		ATK:            c.baseATK(),
+		DEF:            c.baseDef,
+		CritRate:       5,
+		CritDmg:        50,
+		EnergyRecharge: 100,
+	}
+
+	p.percent = copyStats(p.base)
+	for stat, v := range bonus {
+		if _, ok := flatStats[stat]; !ok {
+			p.percent[stat] = p.percent[stat] + v
+		}
+	}
+	for flat, percent := range flatStats {
+		p.percent[flat] = p.base[flat] * (1 + p.percent[percent]/100)
+	}
+
+	p.flat = copyStats(p.percent)
+	for flat := range flatStats {
+		p.flat[flat] = p.flat[flat] + bonus[flat]
+	}
+
+	p.conversions = map[stat]float32{}
+	for _, convert := range c.allConversions(setConversions) {
+		for stat, v := range convert(p.flat) {
+			p.conversions[stat] = p.conversions[stat] + v
+		}
+	}
+
+	p.final = copyStats(p.flat)
+	for stat, v := range p.conversions {
+		p.final[stat] = p.final[stat] + v
+	}
+	return p
+}
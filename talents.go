@@ -0,0 +1,273 @@
+package genshinartis
+
+const MaxTalentLevel = 15
+
+// Most talent multipliers grow with the talent level following one of these tables, as a factor of the level 1 multiplier.
+// Normal, charged and plunging attacks usually follow the physical one, the rest the elemental one.
+var physicalTalentScaling = [MaxTalentLevel]float32{1, 1.0812, 1.1625, 1.2788, 1.3601, 1.4531, 1.5810, 1.7088, 1.8367, 1.9762, 2.1361, 2.3241, 2.5120, 2.7000, 2.9050}
+var elementalTalentScaling = [MaxTalentLevel]float32{1, 1.075, 1.15, 1.25, 1.325, 1.4, 1.5, 1.6, 1.7, 1.8, 1.9, 2, 2.125, 2.25, 2.375}
+
+// checkTalentLevel returns an InvalidValueError if there are no multipliers for the talent level
+func checkTalentLevel(talentLevel int) error {
+	if talentLevel < 1 || talentLevel > MaxTalentLevel {
+		return &InvalidValueError{Field: "talent level", Value: talentLevel, Reason: "out of range"}
+	}
+	return nil
+}
+
+// talentHit is a single hit of a talent
+type talentHit struct {
+	tag                 attackTag
+	physical            bool // deals physical DMG instead of the character element, unless infused
+	offensiveStat       stat
+	multiplier          float32 // at talent level 1
+	secondaryStat       stat    // for hits that scale with two stats, like ATK and EM
+	secondaryMultiplier float32 // at talent level 1, 0 for hits that scale with a single stat
+	scaling             *[MaxTalentLevel]float32
+}
+
+func naHit(tag attackTag, multiplier float32) talentHit {
+	return talentHit{tag, true, ATK, multiplier, 0, 0, &physicalTalentScaling}
+}
+
+func elementalHit(tag attackTag, multiplier float32) talentHit {
+	return talentHit{tag, false, ATK, multiplier, 0, 0, &elementalTalentScaling}
+}
+
+// hpHit is an elemental hit that scales with max HP instead of ATK
+func hpHit(tag attackTag, multiplier float32) talentHit {
+	return talentHit{tag, false, HP, multiplier, 0, 0, &elementalTalentScaling}
+}
+
+// emHit is an elemental hit that scales with both ATK and EM
+func emHit(tag attackTag, atkMultiplier, emMultiplier float32) talentHit {
+	return talentHit{tag, false, ATK, atkMultiplier, ElementalMastery, emMultiplier, &elementalTalentScaling}
+}
+
+// Talent hits by GOOD character key and hit name
+// From https://genshin-impact.fandom.com/wiki/Character/List
+var talents = map[string]map[string]talentHit{
+	"Alhaitham": {
+		"Normal1":          naHit(NormalAttack, 49.53),
+		"Normal2":          naHit(NormalAttack, 50.75),
+		"Normal3":          naHit(NormalAttack, 34.18), // x2
+		"Normal4":          naHit(NormalAttack, 66.77),
+		"Normal5":          naHit(NormalAttack, 83.85),
+		"Charged":          naHit(ChargedAttack, 55.29), // x2
+		"PlungeDMG":        naHit(PlungeAttack, 63.93),
+		"LowPlunge":        naHit(PlungeAttack, 127.84),
+		"HighPlunge":       naHit(PlungeAttack, 159.68),
+		"RushAttack":       emHit(ElementalSkill, 193.6, 154.88),
+		"MirrorProjection": emHit(ElementalSkill, 67.2, 134.4),
+		"BurstHit":         emHit(ElementalBurst, 121.6, 97.28),
+	},
+	"Bennett": {
+		"Normal1":         naHit(NormalAttack, 44.55),
+		"Normal2":         naHit(NormalAttack, 42.74),
+		"Normal3":         naHit(NormalAttack, 54.61),
+		"Normal4":         naHit(NormalAttack, 59.68),
+		"Normal5":         naHit(NormalAttack, 71.9),
+		"Charged1":        naHit(ChargedAttack, 55.9),
+		"Charged2":        naHit(ChargedAttack, 60.72),
+		"PlungeDMG":       naHit(PlungeAttack, 63.93),
+		"LowPlunge":       naHit(PlungeAttack, 127.84),
+		"HighPlunge":      naHit(PlungeAttack, 159.68),
+		"SkillPress":      elementalHit(ElementalSkill, 137.6),
+		"FantasticVoyage": elementalHit(ElementalBurst, 232.8),
+	},
+	"Faruzan": {
+		"Normal1":             naHit(NormalAttack, 44.73),
+		"Normal2":             naHit(NormalAttack, 42.13),
+		"Normal3":             naHit(NormalAttack, 53.05),
+		"Normal4":             naHit(NormalAttack, 70.52),
+		"AimedShot":           naHit(ChargedAttack, 43.86),
+		"ChargeLevel1":        elementalHit(ChargedAttack, 124),
+		"Skill":               elementalHit(ElementalSkill, 148.8),
+		"PressurizedCollapse": elementalHit(ElementalSkill, 108),
+		"DazzlingPolyhedron":  elementalHit(ElementalBurst, 378.4),
+	},
+	"Ganyu": {
+		"Normal1":         naHit(NormalAttack, 31.73),
+		"Normal2":         naHit(NormalAttack, 35.6),
+		"Normal3":         naHit(NormalAttack, 45.49),
+		"Normal4":         naHit(NormalAttack, 45.49),
+		"Normal5":         naHit(NormalAttack, 48.25),
+		"Normal6":         naHit(NormalAttack, 57.62),
+		"AimedShot":       naHit(ChargedAttack, 43.86),
+		"ChargeLevel1":    elementalHit(ChargedAttack, 124),
+		"FrostflakeArrow": elementalHit(ChargedAttack, 128),
+		"FrostflakeBloom": elementalHit(ChargedAttack, 217.6),
+		"IceLotus":        elementalHit(ElementalSkill, 132),
+		"CelestialShower": elementalHit(ElementalBurst, 70.27),
+		"PlungeDMG":       naHit(PlungeAttack, 56.83),
+		"LowPlunge":       naHit(PlungeAttack, 113.63),
+		"HighPlunge":      naHit(PlungeAttack, 141.93),
+	},
+	"HuTao": {
+		"Normal1":        naHit(NormalAttack, 46.89),
+		"Normal2":        naHit(NormalAttack, 48.25),
+		"Normal3":        naHit(NormalAttack, 61.05),
+		"Normal4":        naHit(NormalAttack, 65.64),
+		"Normal5a":       naHit(NormalAttack, 33.27),
+		"Normal5b":       naHit(NormalAttack, 35.2),
+		"Normal6":        naHit(NormalAttack, 85.96),
+		"Charged":        naHit(ChargedAttack, 136.03),
+		"PlungeDMG":      naHit(PlungeAttack, 65.42),
+		"LowPlunge":      naHit(PlungeAttack, 130.81),
+		"HighPlunge":     naHit(PlungeAttack, 163.39),
+		"BloodBlossom":   elementalHit(ElementalSkill, 64),
+		"SpiritSoother":  elementalHit(ElementalBurst, 303.27),
+		"SpiritSootherL": elementalHit(ElementalBurst, 379.09), // below 50% HP
+	},
+	"KaedeharaKazuha": {
+		"Normal1":     naHit(NormalAttack, 44.98),
+		"Normal2":     naHit(NormalAttack, 45.22),
+		"Normal3a":    naHit(NormalAttack, 25.8),
+		"Normal3b":    naHit(NormalAttack, 30.96),
+		"Normal4":     naHit(NormalAttack, 60.72),
+		"Normal5":     naHit(NormalAttack, 25.37), // x3
+		"Charged1":    naHit(ChargedAttack, 43),
+		"Charged2":    naHit(ChargedAttack, 74.63),
+		"PlungeDMG":   naHit(PlungeAttack, 81.83),
+		"LowPlunge":   naHit(PlungeAttack, 163.63),
+		"HighPlunge":  naHit(PlungeAttack, 204.39),
+		"SkillPress":  elementalHit(ElementalSkill, 192),
+		"SkillHold":   elementalHit(ElementalSkill, 260.8),
+		"BurstSlash":  elementalHit(ElementalBurst, 262.4),
+		"BurstDoT":    elementalHit(ElementalBurst, 120),
+		"BurstAbsorb": elementalHit(ElementalBurst, 36), // DMG of the absorbed element
+	},
+	"KukiShinobu": {
+		"Normal1":       naHit(NormalAttack, 48.76),
+		"Normal2":       naHit(NormalAttack, 44.55),
+		"Normal3":       naHit(NormalAttack, 59.34),
+		"Normal4":       naHit(NormalAttack, 71.17),
+		"Charged1":      naHit(ChargedAttack, 55.63),
+		"Charged2":      naHit(ChargedAttack, 66.77),
+		"PlungeDMG":     naHit(PlungeAttack, 63.93),
+		"LowPlunge":     naHit(PlungeAttack, 127.84),
+		"HighPlunge":    naHit(PlungeAttack, 159.68),
+		"Skill":         elementalHit(ElementalSkill, 75.71),
+		"GrassRing":     elementalHit(ElementalSkill, 25.24),
+		"GyoeiKariyama": hpHit(ElementalBurst, 3.6),
+	},
+	// Normal and charged attacks of catalysts follow another scaling, they aren't included
+	"Nahida": {
+		"SkillPress":           elementalHit(ElementalSkill, 98.4),
+		"SkillHold":            elementalHit(ElementalSkill, 130.4),
+		"TriKarmaPurification": emHit(ElementalSkill, 103.2, 206.4),
+	},
+	"RaidenShogun": {
+		"Normal1":           naHit(NormalAttack, 39.65),
+		"Normal2":           naHit(NormalAttack, 39.73),
+		"Normal3":           naHit(NormalAttack, 49.88),
+		"Normal4a":          naHit(NormalAttack, 28.98),
+		"Normal4b":          naHit(NormalAttack, 28.98),
+		"Normal5":           naHit(NormalAttack, 65.45),
+		"Charged":           naHit(ChargedAttack, 99.59),
+		"PlungeDMG":         naHit(PlungeAttack, 63.93),
+		"LowPlunge":         naHit(PlungeAttack, 127.84),
+		"HighPlunge":        naHit(PlungeAttack, 159.68),
+		"Skill":             elementalHit(ElementalSkill, 117.2),
+		"CoordinatedAttack": elementalHit(ElementalSkill, 42),
+		// Without the Chakra Desiderata resolve bonus
+		"MusouNoHitotachi": elementalHit(ElementalBurst, 400.8),
+		"MusouIsshin1":     elementalHit(ElementalBurst, 44.74),
+		"MusouIsshin2":     elementalHit(ElementalBurst, 43.96),
+		"MusouIsshin3":     elementalHit(ElementalBurst, 53.82),
+		"MusouIsshin4a":    elementalHit(ElementalBurst, 30.89),
+		"MusouIsshin4b":    elementalHit(ElementalBurst, 30.98),
+		"MusouIsshin5":     elementalHit(ElementalBurst, 73.94),
+		"MusouIsshinCA1":   elementalHit(ElementalBurst, 61.6),
+		"MusouIsshinCA2":   elementalHit(ElementalBurst, 74.36),
+	},
+	"Xiao": {
+		"Normal1":    naHit(NormalAttack, 27.54), // x2
+		"Normal2":    naHit(NormalAttack, 56.94),
+		"Normal3":    naHit(NormalAttack, 68.55),
+		"Normal4":    naHit(NormalAttack, 37.66), // x2
+		"Normal5":    naHit(NormalAttack, 71.54),
+		"Normal6":    naHit(NormalAttack, 95.83),
+		"Charged":    naHit(ChargedAttack, 121.09),
+		"PlungeDMG":  naHit(PlungeAttack, 81.83),
+		"LowPlunge":  naHit(PlungeAttack, 163.63),
+		"HighPlunge": naHit(PlungeAttack, 204.39),
+		"Skill":      elementalHit(ElementalSkill, 252.8),
+	},
+}
+
+// DMG bonus of Xiao's burst, Bane of All Evil, for normal, charged and plunging attacks at every talent level
+var xiaoBurstDMGBonus = [MaxTalentLevel]float32{58.45, 61.95, 65.45, 70, 73.5, 77, 81.55, 86.1, 90.65, 95.2, 99.75, 104.3, 108.85, 113.4, 117.95}
+
+// Stats that talents give to the character themself, by GOOD character key and talent name
+var talentBuffs = map[string]map[string]func(talentLevel int) map[stat]float32{
+	"Xiao": {
+		// while the burst is active, it also infuses his attacks with Anemo
+		"BaneOfAllEvil": func(talentLevel int) map[stat]float32 {
+			dmg := xiaoBurstDMGBonus[talentLevel-1]
+			return map[stat]float32{NormalAttackDMG: dmg, ChargedAttackDMG: dmg, PlungeDMG: dmg}
+		},
+	},
+}
+
+// talentBuff is a buff from one of the character's own talents
+type talentBuff map[stat]float32
+
+func (b talentBuff) buff() map[stat]float32 {
+	return b
+}
+
+// TalentAttack returns the attack of a talent hit of the character with the GOOD key, at the given talent level.
+// Hits that deal physical DMG can be infused by changing the element of the attack.
+// Only the characters in talents are supported, for now Alhaitham, Bennett, Faruzan, Ganyu, HuTao, KaedeharaKazuha,
+// KukiShinobu, Nahida, RaidenShogun and Xiao: the rest of the characters return an InvalidValueError,
+// unknown ones an UnknownKeyError.
+func TalentAttack(characterKey, hit string, talentLevel int) (attack, error) {
+	hits, ok := talents[characterKey]
+	if !ok {
+		if _, known := characters[characterKey]; known {
+			return attack{}, &InvalidValueError{Field: "character key", Value: characterKey, Reason: "no talent data for the character"}
+		}
+		return attack{}, &UnknownKeyError{Field: "character key", Key: characterKey}
+	}
+	h, ok := hits[hit]
+	if !ok {
+		return attack{}, &UnknownKeyError{Field: "talent hit", Key: hit}
+	}
+	if err := checkTalentLevel(talentLevel); err != nil {
+		return attack{}, err
+	}
+
+	el := characters[characterKey].element
+	if h.physical {
+		el = Physical
+	}
+	return attack{
+		tag:                 h.tag,
+		element:             el,
+		offensiveStat:       h.offensiveStat,
+		multiplier:          h.multiplier * h.scaling[talentLevel-1],
+		secondaryStat:       h.secondaryStat,
+		secondaryMultiplier: h.secondaryMultiplier * h.scaling[talentLevel-1],
+	}, nil
+}
+
+// TalentBuff returns the stats a talent of the character with the GOOD key gives to themself, at the given talent level,
+// to be added to the character's buffs. Errors are like the ones of TalentAttack.
+func TalentBuff(characterKey, talent string, talentLevel int) (talentBuff, error) {
+	buffs, ok := talentBuffs[characterKey]
+	if !ok {
+		if _, known := characters[characterKey]; known {
+			return nil, &InvalidValueError{Field: "character key", Value: characterKey, Reason: "no talent buffs for the character"}
+		}
+		return nil, &UnknownKeyError{Field: "character key", Key: characterKey}
+	}
+	buff, ok := buffs[talent]
+	if !ok {
+		return nil, &UnknownKeyError{Field: "talent", Key: talent}
+	}
+	if err := checkTalentLevel(talentLevel); err != nil {
+		return nil, err
+	}
+	return talentBuff(buff(talentLevel)), nil
+}
@@ -0,0 +1,135 @@
+package genshinartis
+
+import (
+	"container/heap"
+	"sort"
+)
+
+// buildResult is a build found by the optimizer, with the damage breakdown of the character using it
+type buildResult struct {
+	build     map[artifactSlot]*Artifact
+	breakdown damageBreakdown
+	value     float32 // of the breakdown for the objective of the search
+}
+
+// topBuilds keeps the n best builds added to it, as a heap with the worst of them on top
+type topBuilds struct {
+	n       int
+	results []buildResult
+}
+
+func newTopBuilds(n int) *topBuilds {
+	return &topBuilds{n: n, results: make([]buildResult, 0, n+1)}
+}
+
+func (t *topBuilds) Len() int           { return len(t.results) }
+func (t *topBuilds) Less(i, j int) bool { return t.results[i].value < t.results[j].value }
+func (t *topBuilds) Swap(i, j int)      { t.results[i], t.results[j] = t.results[j], t.results[i] }
+func (t *topBuilds) Push(x interface{}) { t.results = append(t.results, x.(buildResult)) }
+func (t *topBuilds) Pop() interface{} {
+	last := t.results[len(t.results)-1]
+	t.results = t.results[:len(t.results)-1]
+	return last
+}
+
+// threshold returns the value a build must beat to be kept, 0 until there are n builds
+func (t *topBuilds) threshold() float32 {
+	if len(t.results) < t.n {
+		return 0
+	}
+	return t.results[0].value
+}
+
+// wants returns true if a build with the value would be kept
+func (t *topBuilds) wants(value float32) bool {
+	return t.n > 0 && value > t.threshold()
+}
+
+// add keeps the result if it's one of the n best builds.
+// The build is copied when kept, so it can be reused.
+func (t *topBuilds) add(r buildResult) bool {
+	if !t.wants(r.value) {
+		return false
+	}
+	build := make(map[artifactSlot]*Artifact, len(r.build))
+	for slot, art := range r.build {
+		build[slot] = art
+	}
+	r.build = build
+	heap.Push(t, r)
+	if len(t.results) > t.n {
+		heap.Pop(t)
+	}
+	return true
+}
+
+// sorted returns the kept builds, the best first
+func (t *topBuilds) sorted() []buildResult {
+	sorted := append([]buildResult{}, t.results...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].value > sorted[j].value })
+	return sorted
+}
+
+// diversity is how many pieces a build can share with the builds picked before it, see findTopContext
+type diversity struct {
+	picked    []map[artifactSlot]*Artifact
+	maxShared int
+}
+
+// allows returns true if the build shares at most maxShared pieces with every picked build
+func (d diversity) allows(build map[artifactSlot]*Artifact) bool {
+	for _, p := range d.picked {
+		if sharedPieces(p, build) > d.maxShared {
+			return false
+		}
+	}
+	return true
+}
+
+// restrict returns the candidates without the pieces no allowed build can use: the pieces of a picked build in the slots
+// left once the slots with a single candidate share maxShared pieces with it.
+// It returns false if the candidates can't make any allowed build.
+func (d diversity) restrict(c candidates) (candidates, bool) {
+	for changed := true; changed; {
+		changed = false
+		for _, p := range d.picked {
+			shared := 0
+			for slot, arts := range c {
+				if len(arts) == 1 && p[artifactSlot(slot)] == arts[0] {
+					shared++
+				}
+			}
+			if shared > d.maxShared {
+				return c, false
+			}
+			if shared < d.maxShared {
+				continue
+			}
+			for slot, arts := range c {
+				if len(arts) < 2 {
+					continue
+				}
+				for i, art := range arts {
+					if art == p[artifactSlot(slot)] {
+						// a new slice, as the candidates share their pieces with the other groups
+						c[slot] = append(append(make([]*Artifact, 0, len(arts)-1), arts[:i]...), arts[i+1:]...)
+						changed = true
+						break
+					}
+				}
+			}
+		}
+	}
+	return c, true
+}
+
+// sharedPieces returns how many slots of both builds have the same artifact
+func sharedPieces(a, b map[artifactSlot]*Artifact) int {
+	shared := 0
+	for slot, art := range a {
+		if b[slot] == art {
+			shared++
+		}
+	}
+	return shared
+}